@@ -1,8 +1,10 @@
 package ast
 
+import "../tokens"
+
 type Node interface {
 	String(indent int) string
-	//StartPos() tokens.Pos
+	StartPos() tokens.Pos // where the first token of the node starts
 }
 
 type Statement interface {
@@ -22,8 +24,13 @@ const INDENT = "\t"
 // -------------------------------------------
 type Program struct {
 	Body BlockStatement
+
+	// comments and blank lines in the order they appear, only kept if the
+	// lexer was asked to keep trivia
+	Trivia []tokens.Token
 }
 
+func (p Program) StartPos() tokens.Pos { return p.Body.StartPos() }
 func (p Program) String(indent int) string {
 	return p.Body.String(indent)
 }
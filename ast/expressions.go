@@ -10,16 +10,16 @@ import (
 // -------------------------------------------
 // --------- EXPRESSION STATEMENT ------------
 // -------------------------------------------
-//type ExpressionStatement struct {
-//	Expression Expression
-//	Token      tokens.Token
-//}
-//
-////func (s ExpressionStatement) Token() tokens.Pos { return s.startPos }
-//func (s ExpressionStatement) statementNode() {}
-//func (s ExpressionStatement) String() string {
-//	return s.Expression.String()
-//}
+type ExpressionStatement struct {
+	Expression Expression
+	Token      tokens.Token
+}
+
+func (s ExpressionStatement) StartPos() tokens.Pos { return s.Token.Pos }
+func (s ExpressionStatement) statementNode()       {}
+func (s ExpressionStatement) String(indent int) string {
+	return s.Expression.String(indent)
+}
 
 // -------------------------------------------
 // ----------- RECORD EXPRESSION -------------
@@ -28,19 +28,32 @@ type RecordExpression struct {
 	Keys   []string
 	Values []Expression
 	Token  tokens.Token
+	End    tokens.Token // the closing }
 }
 
-//func (e RecordExpression) StartPos() tokens.Pos { return e.startPos }
-func (e RecordExpression) expressionNode() {}
+func (e RecordExpression) StartPos() tokens.Pos { return e.Token.Pos }
+func (e RecordExpression) expressionNode()      {}
 func (e RecordExpression) String(indent int) string {
+	if len(e.Keys) == 0 {
+		return "{}"
+	}
+
 	var pairs []string
-	in := strings.Repeat(INDENT, indent)
+	in := strings.Repeat(INDENT, indent+1)
 
 	for i, key := range e.Keys {
-		pairs = append(pairs, fmt.Sprintf("%v%v = %v", in, key, e.Values[i].String(indent+1)))
+		pairs = append(pairs, fmt.Sprintf("%v%v = %v", in, recordKey(key), e.Values[i].String(indent+1)))
 	}
 
-	return fmt.Sprintf("{\n%v\n%v}", strings.Join(pairs, ",\n"), in)
+	return fmt.Sprintf("{\n%v\n%v}", strings.Join(pairs, ",\n"), strings.Repeat(INDENT, indent))
+}
+
+// recordKey quotes keys that can't be written as a bare name
+func recordKey(key string) string {
+	if tokens.IsIdentifier(key) {
+		return key
+	}
+	return Quote(key)
 }
 
 // -------------------------------------------
@@ -52,8 +65,8 @@ type FunctionExpression struct {
 	Token      tokens.Token
 }
 
-//func (e FunctionExpression) StartPos() tokens.Pos { return e.startPos }
-func (e FunctionExpression) expressionNode() {}
+func (e FunctionExpression) StartPos() tokens.Pos { return e.Token.Pos }
+func (e FunctionExpression) expressionNode()      {}
 func (e FunctionExpression) String(indent int) string {
 	var stmts []string
 	in := strings.Repeat(INDENT, indent+1)
@@ -74,10 +87,11 @@ func (e FunctionExpression) String(indent int) string {
 type ListExpression struct {
 	Values []Expression
 	Token  tokens.Token
+	End    tokens.Token // the closing ]
 }
 
-//func (e ListExpression) StartPos() tokens.Pos { return e.startPos }
-func (e ListExpression) expressionNode() {}
+func (e ListExpression) StartPos() tokens.Pos { return e.Token.Pos }
+func (e ListExpression) expressionNode()      {}
 func (e ListExpression) String(indent int) string {
 	if len(e.Values) == 0 {
 		return "[]"
@@ -100,11 +114,11 @@ type CallExpression struct {
 	Function  Expression
 	Arguments []Expression
 	Token     tokens.Token
+	End       tokens.Token // the closing )
 }
 
-//func (e CallExpression) StartPos() tokens.Pos { return e.startPos }
-func (e CallExpression) expressionNode() {}
-func (e CallExpression) statementNode()  {}
+func (e CallExpression) StartPos() tokens.Pos { return e.Function.StartPos() }
+func (e CallExpression) expressionNode()      {}
 func (e CallExpression) String(indent int) string {
 	var args []string
 
@@ -115,6 +129,36 @@ func (e CallExpression) String(indent int) string {
 	return fmt.Sprintf("%v(%v)", e.Function.String(indent), strings.Join(args, ", "))
 }
 
+// -------------------------------------------
+// ------------- DOT EXPRESSION --------------
+// -------------------------------------------
+type DotExpression struct {
+	Left  Expression
+	Name  string
+	Token tokens.Token
+}
+
+func (e DotExpression) StartPos() tokens.Pos { return e.Left.StartPos() }
+func (e DotExpression) expressionNode()      {}
+func (e DotExpression) String(indent int) string {
+	return fmt.Sprintf("%v.%v", e.Left.String(indent), e.Name)
+}
+
+// -------------------------------------------
+// ------------ INDEX EXPRESSION -------------
+// -------------------------------------------
+type IndexExpression struct {
+	Left  Expression
+	Index Expression
+	Token tokens.Token
+}
+
+func (e IndexExpression) StartPos() tokens.Pos { return e.Left.StartPos() }
+func (e IndexExpression) expressionNode()      {}
+func (e IndexExpression) String(indent int) string {
+	return fmt.Sprintf("%v[%v]", e.Left.String(indent), e.Index.String(indent))
+}
+
 // -------------------------------------------
 // ----------- PREFIX EXPRESSION -------------
 // -------------------------------------------
@@ -124,8 +168,8 @@ type PrefixExpression struct {
 	Token     tokens.Token
 }
 
-//func (e PrefixExpression) StartPos() tokens.Pos { return e.startPos }
-func (e PrefixExpression) expressionNode() {}
+func (e PrefixExpression) StartPos() tokens.Pos { return e.Token.Pos }
+func (e PrefixExpression) expressionNode()      {}
 func (e PrefixExpression) String(indent int) string {
 	if e.Operator == tokens.SUB {
 		return "-" + e.RightSide.String(indent)
@@ -144,8 +188,8 @@ type InfixExpression struct {
 	Token     tokens.Token
 }
 
-//func (e InfixExpression) StartPos() tokens.Pos { return e.startPos }
-func (e InfixExpression) expressionNode() {}
+func (e InfixExpression) StartPos() tokens.Pos { return e.LeftSide.StartPos() }
+func (e InfixExpression) expressionNode()      {}
 func (e InfixExpression) String(indent int) string {
 	return fmt.Sprintf("(%v %v %v)", e.LeftSide.String(indent), e.Operator, e.RightSide.String(indent))
 }
@@ -3,6 +3,8 @@ package ast
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"../tokens"
 )
@@ -15,9 +17,9 @@ type IdentifierExpression struct {
 	Token tokens.Token
 }
 
-//func (e IdentifierExpression) StartPos() tokens.Pos { return e.startPos }
-func (e IdentifierExpression) expressionNode()   {}
-func (e IdentifierExpression) String(int) string { return e.Name }
+func (e IdentifierExpression) StartPos() tokens.Pos { return e.Token.Pos }
+func (e IdentifierExpression) expressionNode()      {}
+func (e IdentifierExpression) String(int) string    { return e.Name }
 
 // -------------------------------------------
 // ----------- NUMBER EXPRESSION -------------
@@ -27,8 +29,8 @@ type NumberExpression struct {
 	Token tokens.Token
 }
 
-//func (e NumberExpression) StartPos() tokens.Pos { return e.startPos }
-func (e NumberExpression) expressionNode() {}
+func (e NumberExpression) StartPos() tokens.Pos { return e.Token.Pos }
+func (e NumberExpression) expressionNode()      {}
 func (e NumberExpression) String(int) string {
 	return strconv.FormatFloat(e.Value, 'f', -1, 64)
 }
@@ -41,9 +43,9 @@ type BooleanExpression struct {
 	Token tokens.Token
 }
 
-//func (e BooleanExpression) StartPos() tokens.Pos { return e.startPos }
-func (e BooleanExpression) expressionNode()   {}
-func (e BooleanExpression) String(int) string { return strconv.FormatBool(e.Value) }
+func (e BooleanExpression) StartPos() tokens.Pos { return e.Token.Pos }
+func (e BooleanExpression) expressionNode()      {}
+func (e BooleanExpression) String(int) string    { return strconv.FormatBool(e.Value) }
 
 // -------------------------------------------
 // ------------ TEXT EXPRESSION --------------
@@ -53,6 +55,67 @@ type TextExpression struct {
 	Token tokens.Token
 }
 
-//func (e TextExpression) StartPos() tokens.Pos { return e.startPos }
-func (e TextExpression) expressionNode()   {}
-func (e TextExpression) String(int) string { return fmt.Sprintf("%q", e.Value) }
+func (e TextExpression) StartPos() tokens.Pos { return e.Token.Pos }
+func (e TextExpression) expressionNode()      {}
+func (e TextExpression) String(int) string    { return Quote(e.Value) }
+
+// Quote writes a string literal using the escapes the lexer understands
+func Quote(str string) string {
+	return "\"" + Escape(str) + "\""
+}
+
+// Escape escapes str for use inside a string literal, "{" included
+func Escape(str string) string {
+	var builder strings.Builder
+
+	for _, char := range str {
+		switch char {
+		case '"':
+			builder.WriteString(`\"`)
+		case '\\':
+			builder.WriteString(`\\`)
+		case '{':
+			builder.WriteString(`\{`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		default:
+			if unicode.IsPrint(char) {
+				builder.WriteRune(char)
+			} else {
+				fmt.Fprintf(&builder, `\u{%X}`, char)
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+// -------------------------------------------
+// -------- INTERPOLATION EXPRESSION ---------
+// -------------------------------------------
+type InterpolationExpression struct {
+	Parts []Expression // text parts are TextExpressions
+	Token tokens.Token
+}
+
+func (e InterpolationExpression) StartPos() tokens.Pos { return e.Token.Pos }
+func (e InterpolationExpression) expressionNode()      {}
+func (e InterpolationExpression) String(indent int) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+
+	for _, part := range e.Parts {
+		if text, ok := part.(TextExpression); ok {
+			builder.WriteString(Escape(text.Value))
+		} else {
+			builder.WriteString("{" + part.String(indent) + "}")
+		}
+	}
+
+	builder.WriteByte('"')
+	return builder.String()
+}
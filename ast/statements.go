@@ -11,14 +11,18 @@ import (
 // --------- ASSIGNMENT STATEMENT ------------
 // -------------------------------------------
 type AssignmentStatement struct {
-	Name  string
-	Value Expression
-	Token tokens.Token
+	Target Expression // identifier, dot or index expression
+	Value  Expression
+	Local  bool // local variables never overwrite the outer scope
+	Token  tokens.Token
 }
 
-//func (s AssignmentStatement) StartPos() tokens.Pos { return s.startPos }
+func (s AssignmentStatement) StartPos() tokens.Pos { return s.Token.Pos }
 func (s AssignmentStatement) String(indent int) string {
-	return fmt.Sprintf("%v = %v", s.Name, s.Value.String(indent))
+	if s.Local {
+		return fmt.Sprintf("local %v = %v", s.Target.String(indent), s.Value.String(indent))
+	}
+	return fmt.Sprintf("%v = %v", s.Target.String(indent), s.Value.String(indent))
 }
 func (s AssignmentStatement) statementNode() {}
 
@@ -26,15 +30,15 @@ func (s AssignmentStatement) statementNode() {}
 // ----- SHORTHAND ASSIGNMENT STATEMENT ------
 // -------------------------------------------
 type ShorthandAssignmentStatement struct {
-	Name     string
+	Target   Expression // identifier, dot or index expression
 	Value    Expression
 	Operator tokens.TokenType
 	Token    tokens.Token
 }
 
-//func (s AssignmentStatement) StartPos() tokens.Pos { return s.startPos }
+func (s ShorthandAssignmentStatement) StartPos() tokens.Pos { return s.Token.Pos }
 func (s ShorthandAssignmentStatement) String(indent int) string {
-	return fmt.Sprintf("%v %v %v", s.Name, s.Operator, s.Value.String(indent))
+	return fmt.Sprintf("%v %v %v", s.Target.String(indent), s.Operator, s.Value.String(indent))
 }
 func (s ShorthandAssignmentStatement) statementNode() {}
 
@@ -47,8 +51,8 @@ type IfStatement struct {
 	Token        tokens.Token
 }
 
-//func (e IfStatement) StartPos() tokens.Pos { return e.startPos }
-func (e IfStatement) statementNode() {}
+func (e IfStatement) StartPos() tokens.Pos { return e.Token.Pos }
+func (e IfStatement) statementNode()       {}
 func (e IfStatement) String(indent int) string {
 	var stmts []string
 
@@ -67,8 +71,8 @@ type ReturnStatement struct {
 	Token tokens.Token
 }
 
-//func (s ReturnStatement) StartPos() tokens.Pos { return s.startPos }
-func (s ReturnStatement) statementNode() {}
+func (s ReturnStatement) StartPos() tokens.Pos { return s.Token.Pos }
+func (s ReturnStatement) statementNode()       {}
 func (s ReturnStatement) String(indent int) string {
 	if s.Value == nil {
 		return fmt.Sprintf("return")
@@ -85,22 +89,45 @@ type LoopStatement struct {
 	Token tokens.Token
 }
 
-//func (s LoopStatement) StartPos() tokens.Pos { return s.startPos }
+func (s LoopStatement) StartPos() tokens.Pos { return s.Token.Pos }
 func (s LoopStatement) String(indent int) string {
 	return fmt.Sprintf("loop\n%v%vend", s.Body.String(indent+1), strings.Repeat(INDENT, indent))
 }
 func (s LoopStatement) statementNode() {}
 
+// -------------------------------------------
+// ------------ BREAK STATEMENT --------------
+// -------------------------------------------
+type BreakStatement struct {
+	Token tokens.Token
+}
+
+func (s BreakStatement) StartPos() tokens.Pos { return s.Token.Pos }
+func (s BreakStatement) statementNode()       {}
+func (s BreakStatement) String(int) string    { return "break" }
+
+// -------------------------------------------
+// ----------- CONTINUE STATEMENT ------------
+// -------------------------------------------
+type ContinueStatement struct {
+	Token tokens.Token
+}
+
+func (s ContinueStatement) StartPos() tokens.Pos { return s.Token.Pos }
+func (s ContinueStatement) statementNode()       {}
+func (s ContinueStatement) String(int) string    { return "continue" }
+
 // -------------------------------------------
 // ------------ BLOCK STATEMENT --------------
 // -------------------------------------------
 type BlockStatement struct {
 	Statements []Statement
 	Token      tokens.Token
+	End        tokens.Token // the token that closed the block, like end or else
 }
 
-//func (e BlockStatement) StartPos() tokens.Pos { return e.startPos }
-func (e BlockStatement) statementNode() {}
+func (e BlockStatement) StartPos() tokens.Pos { return e.Token.Pos }
+func (e BlockStatement) statementNode()       {}
 func (e BlockStatement) String(indent int) string {
 	var stmts []string
 
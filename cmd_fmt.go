@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"./format"
+)
+
+// formatCommand runs "monkey fmt [--check] [files...]". Files are formatted in
+// place, without any the script on stdin is formatted to stdout. With --check
+// nothing is written, unformatted files are listed and the exit code is 1.
+// Syntax errors and unreadable files give exit code 2.
+func formatCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flags.Bool("check", false, "list unformatted files instead of formatting them")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		source, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+
+		formatted, ok := formatSource("<stdin>", string(source))
+
+		switch {
+		case !ok:
+			return 2
+		case !*check:
+			fmt.Print(formatted)
+		case formatted != string(source):
+			fmt.Println("<stdin>")
+			return 1
+		}
+		return 0
+	}
+
+	status := 0
+
+	for _, filename := range flags.Args() {
+		source, err := ioutil.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 2
+			continue
+		}
+
+		formatted, ok := formatSource(filename, string(source))
+
+		if !ok {
+			status = 2
+			continue
+		}
+
+		if formatted == string(source) {
+			continue
+		}
+
+		if *check {
+			fmt.Println(filename)
+			if status == 0 {
+				status = 1
+			}
+			continue
+		}
+
+		info, err := os.Stat(filename)
+		if err == nil {
+			err = ioutil.WriteFile(filename, []byte(formatted), info.Mode())
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 2
+		}
+	}
+
+	return status
+}
+
+func formatSource(filename string, source string) (string, bool) {
+	formatted, errs := format.Source(source)
+
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err.Format(filename, source))
+	}
+
+	return formatted, errs == nil
+}
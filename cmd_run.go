@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"./evaluator"
+	"./lexer"
+	"./object"
+	"./parser"
+	"./repl"
+)
+
+// runCommand runs a script with args as its os.args and returns the exit
+// code: 0 if it ran to the end, 1 if it stopped with an error and 2 if it
+// couldn't be read or has syntax errors. In the tokens and ast modes the
+// script is printed at that stage instead of being run.
+func runCommand(filename string, code string, args []string, mode repl.Mode, tokenFormat string) int {
+	input, err := openScript(filename, code)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer input.Close()
+
+	if mode == repl.TOKENS {
+		if err := repl.DumpTokens(input, os.Stdout, tokenFormat); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		return 0
+	}
+
+	// stdin can only be read once, so a copy of it is kept to show the lines
+	// errors point at. Files are read again for that instead.
+	var stdin bytes.Buffer
+	reader := io.Reader(input)
+
+	if code == "" && filename == "-" {
+		reader = io.TeeReader(input, &stdin)
+	}
+
+	source := func() string {
+		switch {
+		case code != "":
+			return code
+		case filename == "-":
+			return stdin.String()
+		}
+
+		content, _ := ioutil.ReadFile(filename)
+		return string(content)
+	}
+
+	name := filename
+	if filename == "-" {
+		name = "<stdin>"
+	}
+
+	pars := parser.New(lexer.NewReader(reader))
+	program := pars.ParseProgram()
+
+	if pars.HasErrors() {
+		source := source()
+		for _, err := range pars.Errors() {
+			fmt.Fprintln(os.Stderr, err.Format(name, source))
+		}
+		return 2
+	}
+
+	if mode == repl.AST {
+		fmt.Print(program.String(0))
+		return 0
+	}
+
+	evaluator.SetArgs(args)
+
+	if err, ok := evaluator.Eval(program, object.NewEnvironment()).(object.Error); ok {
+		fmt.Fprintln(os.Stderr, err.Format(name, source()))
+		return 1
+	}
+
+	return 0
+}
@@ -2,6 +2,8 @@ package evaluator
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"../ast"
 	"../object"
@@ -9,11 +11,23 @@ import (
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+
+	// errors get the position of the innermost node they came from
+	if err, ok := result.(object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.StartPos()
+		return err
+	}
+
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	// Statements
 	case ast.Program:
-		return evalProgram(node.Body, env)
+		return evalProgram(node.Body.Statements, env)
 	case ast.ExpressionStatement:
 		return Eval(node.Expression, env)
 	case ast.AssignmentStatement:
@@ -21,13 +35,28 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(value) {
 			return value
 		}
-		env.Set(node.Name, value)
-		return nil
+		if node.Local {
+			env.SetLocal(node.Target.(ast.IdentifierExpression).Name, value)
+			return nil
+		}
+		return evalAssignment(node.Target, value, env)
+	case ast.ShorthandAssignmentStatement:
+		return evalShorthandAssignment(node, env)
 	case ast.IfStatement:
 		return evalIfStatement(node, env)
 	case ast.BlockStatement:
 		return evalBlockStatements(node.Statements, env)
+	case ast.LoopStatement:
+		return evalLoopStatement(node, env)
+	case ast.BreakStatement:
+		return object.Break{}
+	case ast.ContinueStatement:
+		return object.Continue{}
 	case ast.ReturnStatement:
+		if node.Value == nil {
+			return object.ReturnValue{Value: object.Nil{}}
+		}
+
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
@@ -48,6 +77,22 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return right
 		}
 		return evalInfixExpression(node.Operator, left, right)
+	case ast.DotExpression:
+		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		return evalDotExpression(left, node.Name)
+	case ast.IndexExpression:
+		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
 	case ast.PrefixExpression:
 		right := Eval(node.RightSide, env)
 		if isError(right) {
@@ -58,8 +103,18 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return object.Number(node.Value)
 	case ast.TextExpression:
 		return object.String(node.Value)
+	case ast.InterpolationExpression:
+		return evalInterpolationExpression(node, env)
 	case ast.BooleanExpression:
 		return object.Boolean(node.Value)
+	case ast.ListExpression:
+		values, err := evalExpressions(node.Values, env)
+		if err != nil {
+			return err
+		}
+		return object.List(values)
+	case ast.RecordExpression:
+		return evalRecordExpression(node, env)
 	case ast.FunctionExpression:
 		return object.Function{Parameters: node.Parameters, Body: &node.Body, Env: env}
 
@@ -82,36 +137,259 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 }
 
 func evalIdentifier(identifier ast.IdentifierExpression, env *object.Environment) object.Object {
-	if value, ok := env.Get(identifier.Name); ok {
+	if value, ok := Lookup(identifier.Name, env); ok {
 		return value
 	}
 
-	if builtin, ok := builtins[identifier.Name]; ok {
-		return builtin
+	return newErrorF("could not find identifier %q", identifier.Name)
+}
+
+// Lookup finds the value of name the way an identifier in a script does,
+// variables in env first and then the builtins
+func Lookup(name string, env *object.Environment) (object.Object, bool) {
+	if value, ok := env.Get(name); ok {
+		return value, true
 	}
 
-	return newErrorF("could not find identifier %q", identifier.Name)
+	builtin, ok := builtins[name]
+	return builtin, ok
 }
 
-func evalIfStatement(node ast.IfStatement, env *object.Environment) object.Object {
-	condition := Eval(node.Conditions, env)
-	if isError(condition) {
-		return condition
+// Names returns every name Lookup can find in env, sorted
+func Names(env *object.Environment) []string {
+	names := env.Names()
+
+	for name := range builtins {
+		if _, ok := env.Get(name); !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func evalDotExpression(left object.Object, name string) object.Object {
+	record, ok := left.(object.Record)
+
+	if !ok {
+		return newErrorF("cannot get field %q from type %v", name, left.Type())
+	}
+
+	value, ok := record.Values[name]
+
+	if !ok {
+		return newErrorF("record has no field %q", name)
+	}
+
+	return value
+}
+
+func evalIndexExpression(left object.Object, index object.Object) object.Object {
+	switch left := left.(type) {
+	case object.List:
+		i, err := listIndex(left, index)
+		if err != nil {
+			return err
+		}
+		return left[i]
+	case object.Record:
+		if index.Type() != object.STRING {
+			return newErrorF("record keys must be strings, got %v", index.Type())
+		}
+		// unlike dot access a missing key is not an error, so scripts can check for it
+		return left.Get(index.String())
+	default:
+		return newErrorF("cannot index into type %v", left.Type())
+	}
+}
+
+// listIndex converts index into a position in list, counting negative indexes from the end
+func listIndex(list object.List, index object.Object) (int, object.Object) {
+	number, ok := index.(object.Number)
+
+	if !ok {
+		return 0, newErrorF("list indexes must be numbers, got %v", index.Type())
+	}
+
+	i := int(number)
+
+	if object.Number(i) != number {
+		return 0, newErrorF("list indexes must be whole numbers, got %v", number)
+	}
+
+	if i < 0 {
+		i += len(list)
+	}
+
+	if i < 0 || i >= len(list) {
+		return 0, newErrorF("index %v out of range for list of length %v", number, len(list))
+	}
+
+	return i, nil
+}
+
+func evalAssignment(target ast.Expression, value object.Object, env *object.Environment) object.Object {
+	if identifier, ok := target.(ast.IdentifierExpression); ok {
+		env.Set(identifier.Name, value)
+		return nil
+	}
+
+	left, index, err := evalAssignmentTarget(target, env)
+	if err != nil {
+		return err
 	}
 
-	if condition.Bool() {
-		Eval(node.Consequences, env)
+	return evalIndexAssignment(left, index, value)
+}
+
+var shorthandOperators = map[tokens.TokenType]tokens.TokenType{
+	tokens.ADD_ASSIGN: tokens.ADD,
+	tokens.SUB_ASSIGN: tokens.SUB,
+	tokens.MUL_ASSIGN: tokens.MUL,
+	tokens.DIV_ASSIGN: tokens.DIV,
+}
+
+func evalShorthandAssignment(node ast.ShorthandAssignmentStatement, env *object.Environment) object.Object {
+	value := Eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	if identifier, ok := node.Target.(ast.IdentifierExpression); ok {
+		current, ok := env.Get(identifier.Name)
+		if !ok {
+			return newErrorF("cannot use %v on undefined variable %q", node.Operator, identifier.Name)
+		}
+
+		result := evalInfixExpression(shorthandOperators[node.Operator], current, value)
+		if isError(result) {
+			return result
+		}
+
+		// write back to the scope the variable lives in, not the current one
+		env.Update(identifier.Name, result)
+		return nil
+	}
+
+	// the container and index are only evaluated once, as in xs[next()] += 1
+	left, index, err := evalAssignmentTarget(node.Target, env)
+	if err != nil {
+		return err
+	}
+
+	var current object.Object
+
+	if dot, ok := node.Target.(ast.DotExpression); ok {
+		current = evalDotExpression(left, dot.Name)
+	} else {
+		current = evalIndexExpression(left, index)
+	}
+
+	if isError(current) {
+		return current
+	}
+
+	result := evalInfixExpression(shorthandOperators[node.Operator], current, value)
+	if isError(result) {
+		return result
+	}
+
+	return evalIndexAssignment(left, index, result)
+}
+
+// evalAssignmentTarget evaluates the container and index of a dot or index expression
+func evalAssignmentTarget(target ast.Expression, env *object.Environment) (object.Object, object.Object, object.Object) {
+	switch target := target.(type) {
+	case ast.DotExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return nil, nil, left
+		}
+		return left, object.String(target.Name), nil
+	case ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return nil, nil, left
+		}
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return nil, nil, index
+		}
+		return left, index, nil
+	default:
+		return nil, nil, newErrorF("cannot assign to %v", target.String(0))
+	}
+}
+
+func evalIndexAssignment(left object.Object, index object.Object, value object.Object) object.Object {
+	switch left := left.(type) {
+	case object.List:
+		i, err := listIndex(left, index)
+		if err != nil {
+			return err
+		}
+		left[i] = value
+		return nil
+	case object.Record:
+		if left.Stoned {
+			return newErrorF("cannot change a built-in record")
+		}
+		if index.Type() != object.STRING {
+			return newErrorF("record keys must be strings, got %v", index.Type())
+		}
+		left.Values[index.String()] = value
+		return nil
+	default:
+		return newErrorF("cannot assign to an index of type %v", left.Type())
+	}
+}
+
+func evalIfStatement(node ast.IfStatement, env *object.Environment) object.Object {
+	for i, condition := range node.Conditions {
+		value := Eval(condition, env)
+		if isError(value) {
+			return value
+		}
+
+		if value.Bool() {
+			return evalBlockStatements(node.Consequences[i].Statements, env)
+		}
 	}
 
-	// TODO: Loop
-	//else if node.ElseBlock != nil {
-	//	return Eval(node.ElseBlock, env)
-	//} else {
-	//	return NULL
-	//}
 	return nil
 }
 
+func evalRecordExpression(node ast.RecordExpression, env *object.Environment) object.Object {
+	record := object.Record{Values: make(map[string]object.Object, len(node.Keys))}
+
+	for i, key := range node.Keys {
+		value := Eval(node.Values[i], env)
+		if isError(value) {
+			return value
+		}
+
+		record.Values[key] = value
+	}
+
+	return record
+}
+
+func evalInterpolationExpression(node ast.InterpolationExpression, env *object.Environment) object.Object {
+	parts, err := evalExpressions(node.Parts, env)
+	if err != nil {
+		return err
+	}
+
+	var builder strings.Builder
+
+	for _, part := range parts {
+		builder.WriteString(part.String())
+	}
+
+	return object.String(builder.String())
+}
+
 func evalExpressions(expressions []ast.Expression, env *object.Environment) ([]object.Object, object.Object) {
 	var result []object.Object
 
@@ -128,7 +406,11 @@ func evalExpressions(expressions []ast.Expression, env *object.Environment) ([]o
 	return result, nil
 }
 
+// evalProgram returns the value of the program's return statement or, if it
+// doesn't return, the value of its last statement so the REPL can show it
 func evalProgram(statements []ast.Statement, env *object.Environment) object.Object {
+	var last object.Object
+
 	for _, statement := range statements {
 		result := Eval(statement, env)
 
@@ -137,17 +419,36 @@ func evalProgram(statements []ast.Statement, env *object.Environment) object.Obj
 			return result.Value
 		case object.Error:
 			return result
+		case object.Break, object.Continue:
+			return newErrorF("%v outside of loop", result)
 		}
+
+		last = result
 	}
 
-	return nil
+	return last
 }
 
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case object.Function:
+		if len(args) != len(fn.Parameters) {
+			return newErrorF("function expects %v arguments, got %v", len(fn.Parameters), len(args))
+		}
+
 		extendedEnv := extendedFunctionEnv(fn, args)
-		return unwrapReturnValue(Eval(fn.Body, extendedEnv))
+		result := unwrapReturnValue(evalBlockStatements(fn.Body.Statements, extendedEnv))
+
+		// loop signals never cross a function boundary
+		if result != nil && (result.Type() == object.BREAK || result.Type() == object.CONTINUE) {
+			return newErrorF("%v outside of loop", result)
+		}
+
+		if result == nil {
+			return object.Nil{}
+		}
+
+		return result
 	case object.BuiltinFunction:
 		return fn(args...)
 	default:
@@ -158,8 +459,9 @@ func applyFunction(fn object.Object, args []object.Object) object.Object {
 func extendedFunctionEnv(function object.Function, args []object.Object) *object.Environment {
 	env := object.NewEnclosedEnvironment(function.Env)
 
+	// parameters shadow outer variables with the same name
 	for i, param := range function.Parameters {
-		env.Set(param, args[i])
+		env.SetLocal(param, args[i])
 	}
 
 	return env
@@ -181,7 +483,8 @@ func evalBlockStatements(statements []ast.Statement, env *object.Environment) ob
 			continue
 		}
 
-		if result.Type() == object.RETURN || result.Type() == object.ERROR {
+		switch result.Type() {
+		case object.RETURN, object.ERROR, object.BREAK, object.CONTINUE:
 			return result
 		}
 	}
@@ -189,8 +492,25 @@ func evalBlockStatements(statements []ast.Statement, env *object.Environment) ob
 	return nil
 }
 
+func evalLoopStatement(node ast.LoopStatement, env *object.Environment) object.Object {
+	for {
+		result := evalBlockStatements(node.Body.Statements, env)
+
+		if result == nil {
+			continue
+		}
+
+		switch result.Type() {
+		case object.BREAK:
+			return nil
+		case object.RETURN, object.ERROR:
+			return result
+		}
+	}
+}
+
 func newErrorF(format string, args ...interface{}) object.Error {
-	return object.Error(fmt.Sprintf(format, args...))
+	return object.Error{Message: fmt.Sprintf(format, args...)}
 }
 
 func isError(obj object.Object) bool {
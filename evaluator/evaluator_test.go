@@ -0,0 +1,381 @@
+package evaluator
+
+import (
+	"testing"
+
+	"../lexer"
+	"../object"
+	"../parser"
+)
+
+func TestLoopStatement(t *testing.T) {
+	testEval(t, `
+		i = 0
+
+		loop
+			i = i + 1
+			break
+			i = 100
+		end
+
+		return i
+	`, "1")
+
+	testEval(t, `
+		f = func ()
+			loop
+				return "done"
+			end
+		end
+
+		return f()
+	`, "done")
+
+	testEval(t, `
+		f = func ()
+			break
+		end
+
+		loop
+			x = f()
+		end
+	`, "ERROR: break outside of loop")
+
+	// break and continue inside a branch belong to the loop around it
+	testEval(t, `
+		i = 0
+		odd = false
+		evens = 0
+
+		loop
+			i = i + 1
+
+			if i > 10 then
+				break
+			end
+
+			odd = not odd
+
+			if odd then
+				continue
+			end
+
+			evens = evens + 1
+		end
+
+		return evens
+	`, "5")
+
+	testEval(t, "continue", "ERROR: continue outside of loop")
+}
+
+func TestIfStatement(t *testing.T) {
+	testEval(t, `
+		pick = func (n)
+			if n == 1 then
+				return "one"
+			elseif n == 2 then
+				return "two"
+			elseif n > 2 then
+				return "many"
+			else
+				return "none"
+			end
+		end
+
+		return pick(1) + " " + pick(2) + " " + pick(5) + " " + pick(0)
+	`, "one two many none")
+
+	testEval(t, `
+		f = func ()
+			return
+		end
+
+		return f()
+	`, "nil")
+}
+
+func TestRecordExpression(t *testing.T) {
+	testEval(t, `return {}`, "{}")
+	testEval(t, `return {name = "ann"}`, "{\nname = ann}")
+	testEval(t, `
+		inner = {x = 1 + 1,}
+		return {"with space" = inner}
+	`, "{\nwith space = {\nx = 2}}")
+
+	testEval(t, `return {a = 1} == {a = 1}`, "true")
+	testEval(t, `return {a = 1} == {b = 1}`, "false")
+	testEval(t, `return {a = 1, b = 2} != {a = 1, c = 2}`, "true")
+}
+
+func TestDotExpression(t *testing.T) {
+	testEval(t, `
+		rec = {inner = {name = "ann"}}
+		return rec.inner.name
+	`, "ann")
+
+	testEval(t, `return path.join("a", "b")`, "a/b")
+	testEval(t, "rec = {} return rec.a", `ERROR: record has no field "a"`)
+	testEval(t, "x = 1 return x.a", `ERROR: cannot get field "a" from type number`)
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	testEval(t, `
+		a = 10
+		a += 5
+		a -= 1
+		a *= 2
+		a /= 4
+
+		s = "hello"
+		s += " world"
+
+		return [a, s]
+	`, "[\n7,\nhello world]")
+
+	testEval(t, `
+		xs = [1, 2, 3]
+		rec = {key = 1, list = [10, 20]}
+
+		xs[-1] += 5
+		rec.key += 1
+		rec.list[1] /= 4
+
+		return [xs[2], rec.key, rec.list[1]]
+	`, "[\n8,\n2,\n5]")
+
+	testEval(t, "x += 1", `ERROR: cannot use += on undefined variable "x"`)
+	testEval(t, `x = 1 x += "a"`, "ERROR: type mismatch number + string")
+}
+
+func TestClosures(t *testing.T) {
+	testEval(t, `
+		counter = func ()
+			count = 0
+
+			return func ()
+				count += 1
+				return count
+			end
+		end
+
+		first = counter()
+		second = counter()
+		first()
+		first()
+		second()
+
+		return [first(), second()]
+	`, "[\n3,\n2]")
+}
+
+func TestNestedFunctions(t *testing.T) {
+	testEval(t, `
+		total = 0
+
+		outer = func (x)
+			inner = func (y)
+				total += x * y
+			end
+
+			inner(2)
+			inner(3)
+		end
+
+		outer(10)
+		return total
+	`, "50")
+}
+
+func TestOuterScopeAssignment(t *testing.T) {
+	testEval(t, `
+		a = 5
+
+		change_a = func ()
+			a = 6
+		end
+
+		change_a()
+		return a
+	`, "6")
+
+	testEval(t, `
+		f = func ()
+			b = 1
+		end
+
+		f()
+		return b
+	`, `ERROR: could not find identifier "b"`)
+}
+
+func TestLocalAssignment(t *testing.T) {
+	testEval(t, `
+		a = 5
+
+		f = func ()
+			local a = 6
+			a = 7
+			return a
+		end
+
+		return [f(), a]
+	`, "[\n7,\n5]")
+}
+
+func TestShadowedParameters(t *testing.T) {
+	testEval(t, `
+		a = 1
+
+		f = func (a)
+			a = a + 10
+			return a
+		end
+
+		return [f(2), a]
+	`, "[\n12,\n1]")
+}
+
+func TestIndexExpression(t *testing.T) {
+	testEval(t, `
+		xs = [1, 2, 3]
+		rec = {key = 1, list = [10, 20]}
+
+		xs[0] = 100
+		xs[-1] = 8
+		rec.key = 2
+		rec["new"] = "a"
+		rec.list[1] = 5
+
+		return [xs[0], xs[2], rec.key, rec.new, rec.list[-1], rec["missing"]]
+	`, "[\n100,\n8,\n2,\na,\n5,\nnil]")
+
+	testEval(t, "xs = [1] return xs[1]", "ERROR: index 1 out of range for list of length 1")
+	testEval(t, "xs = [1] return xs[0.5]", "ERROR: list indexes must be whole numbers, got 0.5")
+	testEval(t, "fs.read = 1", "ERROR: cannot change a built-in record")
+}
+
+func TestListCallbacks(t *testing.T) {
+	testEval(t, `
+		xs = [1, 2, 3, 4]
+		factor = 10
+		sum = 0
+
+		list.each(xs, func (x)
+			sum += x
+		end)
+
+		scaled = list.map(xs, func (x)
+			return x * factor
+		end)
+
+		big = list.filter(xs, func (x)
+			return x > 2
+		end)
+
+		product = list.reduce(xs, func (acc, x)
+			return acc * x
+		end, 1)
+
+		return [sum, scaled[-1], big[0], product]
+	`, "[\n10,\n40,\n3,\n24]")
+
+	testEval(t, `
+		return list.map([1, "a"], func (x)
+			return x + 1
+		end)
+	`, "ERROR: type mismatch string + number")
+
+	testEval(t, `
+		return list.map([1], func (a, b)
+			return a
+		end)
+	`, "ERROR: function expects 2 arguments, got 1")
+}
+
+func TestInterpolation(t *testing.T) {
+	testEval(t, `
+		name = "ann"
+		count = 2
+		xs = [1, "two"]
+		return "hello {name}, you have {count + 1} items: {xs[1]} {{}["x"]} {true} \{x}"
+	`, "hello ann, you have 3 items: two nil true {x}")
+
+	testEval(t, `
+		greet = func (who)
+			return "hi {who}"
+		end
+		return "{greet("{1}{2}")}!"
+	`, "hi 12!")
+}
+
+func TestScriptArgs(t *testing.T) {
+	SetArgs([]string{"input.txt", "-v"})
+	defer SetArgs(nil)
+
+	testEval(t, `
+		args = os.args
+		return "{args[0]} {args[1]}"
+	`, "input.txt -v")
+
+	SetArgs(nil)
+	testEval(t, "return os.args", "[]")
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"x = 1\ny = x + \"a\"",
+			"script.mk:2:5: type mismatch number + string\ny = x + \"a\"\n    ^",
+		},
+		{
+			"f = func ()\n\treturn math.max(1, \"a\")\nend\nf()",
+			"script.mk:2:9: math.max expects number as argument, got string\n\treturn math.max(1, \"a\")\n\t       ^",
+		},
+		{
+			"x = 1\ny = \"total: {x + \"a\"}\"",
+			"script.mk:2:14: type mismatch number + string\ny = \"total: {x + \"a\"}\"\n             ^",
+		},
+		{
+			"xs = [1]\nxs[0] = missing",
+			"script.mk:2:9: could not find identifier \"missing\"\nxs[0] = missing\n        ^",
+		},
+	}
+
+	for _, test := range tests {
+		pars := parser.New(lexer.New(test.input))
+		result := Eval(pars.ParseProgram(), object.NewEnvironment())
+		err, ok := result.(object.Error)
+
+		if !ok {
+			t.Fatalf("expected an error, got %v", result)
+		}
+
+		if err.Format("script.mk", test.input) != test.expected {
+			t.Fatalf("expected error:\n%v\ninstead we got:\n%v", test.expected, err.Format("script.mk", test.input))
+		}
+	}
+}
+
+func testEval(t *testing.T, input string, expected string) {
+	pars := parser.New(lexer.New(input))
+	program := pars.ParseProgram()
+
+	if pars.HasErrors() {
+		pars.PrintErrors()
+		t.Fatalf("parser found errors in:\n%v", input)
+	}
+
+	result := Eval(program, object.NewEnvironment())
+
+	if result == nil {
+		t.Fatalf("expected %q, got nil", expected)
+	}
+
+	if result.String() != expected {
+		t.Fatalf("expected result to have string:\n%q\ninstead we got:\n%q", expected, result.String())
+	}
+}
@@ -7,7 +7,11 @@ import (
 	"../object"
 )
 
-var (
+var builtins map[string]object.Object
+
+// builtins are set up in init since the stdlib calls back into the evaluator,
+// which would otherwise be an initialization cycle
+func init() {
 	builtins = map[string]object.Object{
 		"print": object.BuiltinFunction(func(args ...object.Object) object.Object {
 			strs := make([]string, len(args))
@@ -27,23 +31,30 @@ var (
 		"list":   stdList,
 		"log":    stdLog,
 		"math":   stdMath,
+		"os":     stdOs,
 		"path":   stdPath,
 		"record": stdRecord,
 		"string": stdString,
 		"time":   stdTime,
 	}
-)
+}
+
+// callFunction lets builtins call any function value, script defined or
+// builtin. Errors are returned like any other result and should be passed on.
+func callFunction(fn object.Object, args ...object.Object) object.Object {
+	return applyFunction(fn, args)
+}
 
 func checkArgLength(name string, args []object.Object, length int) object.Object {
 	if len(args) != length {
-		return object.Error(fmt.Sprintf("%v expects %v arguments, got %v", name, length, args))
+		return object.Error{Message: fmt.Sprintf("%v expects %v arguments, got %v", name, length, args)}
 	}
 	return nil
 }
 
 func checkArgLengthAtLeast(name string, args []object.Object, length int) object.Object {
 	if len(args) <= length {
-		return object.Error(fmt.Sprintf("%v expects at least %v arguments, got %v", name, length, args))
+		return object.Error{Message: fmt.Sprintf("%v expects at least %v arguments, got %v", name, length, args)}
 	}
 	return nil
 }
@@ -51,7 +62,7 @@ func checkArgLengthAtLeast(name string, args []object.Object, length int) object
 func checkAllArgType(name string, args []object.Object, typ object.Type) object.Object {
 	for _, arg := range args {
 		if arg.Type() != typ {
-			return object.Error(fmt.Sprintf("%v expects %v as argument, got %v", name, typ, arg.Type()))
+			return object.Error{Message: fmt.Sprintf("%v expects %v as argument, got %v", name, typ, arg.Type())}
 		}
 	}
 	return nil
@@ -59,21 +70,21 @@ func checkAllArgType(name string, args []object.Object, typ object.Type) object.
 
 func checkFirstArgType(name string, arg object.Object, typ object.Type) object.Object {
 	if arg.Type() != typ {
-		return object.Error(fmt.Sprintf("%v expects a %v as its first argument, got %v", name, typ, arg.Type()))
+		return object.Error{Message: fmt.Sprintf("%v expects a %v as its first argument, got %v", name, typ, arg.Type())}
 	}
 	return nil
 }
 
 func checkSecondArgType(name string, arg object.Object, typ object.Type) object.Object {
 	if arg.Type() != typ {
-		return object.Error(fmt.Sprintf("%v expects a %v as its second argument, got %v", name, typ, arg.Type()))
+		return object.Error{Message: fmt.Sprintf("%v expects a %v as its second argument, got %v", name, typ, arg.Type())}
 	}
 	return nil
 }
 
 func checkThirdArgType(name string, arg object.Object, typ object.Type) object.Object {
 	if arg.Type() != typ {
-		return object.Error(fmt.Sprintf("%v expects a %v as its third argument, got %v", name, typ, arg.Type()))
+		return object.Error{Message: fmt.Sprintf("%v expects a %v as its third argument, got %v", name, typ, arg.Type())}
 	}
 	return nil
 }
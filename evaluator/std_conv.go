@@ -23,7 +23,7 @@ var stdConv = object.Record{
 			value, err := strconv.ParseFloat(args[0].String(), 64)
 
 			if err != nil {
-				return object.Error("conv.number: " + err.Error())
+				return object.Error{Message: "conv.number: " + err.Error()}
 			}
 
 			return object.Number(value)
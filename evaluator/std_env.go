@@ -31,7 +31,7 @@ var stdEnv = object.Record{
 			err := os.Setenv(args[0].String(), args[1].String())
 
 			if err != nil {
-				return object.Error("env.set: " + err.Error())
+				return object.Error{Message: "env.set: " + err.Error()}
 			}
 			return object.Nil{}
 		}),
@@ -31,7 +31,7 @@ var stdFs = object.Record{
 			err := os.MkdirAll(dir, 0777)
 
 			if err != nil {
-				return object.Error("fs.mkdir: " + err.Error())
+				return object.Error{Message: "fs.mkdir: " + err.Error()}
 			}
 
 			return object.Nil{}
@@ -53,7 +53,7 @@ var stdFs = object.Record{
 			} else if os.IsNotExist(err) {
 				return object.Boolean(false)
 			} else {
-				return object.Error("fs.exists: " + err.Error())
+				return object.Error{Message: "fs.exists: " + err.Error()}
 			}
 		}),
 		"files": object.BuiltinFunction(func(args ...object.Object) object.Object {
@@ -71,7 +71,7 @@ var stdFs = object.Record{
 			dir, err := ioutil.ReadDir(dirPath)
 
 			if err != nil {
-				return object.Error("fs.files: " + err.Error())
+				return object.Error{Message: "fs.files: " + err.Error()}
 			}
 
 			for _, file := range dir {
@@ -97,7 +97,7 @@ var stdFs = object.Record{
 			dir, err := ioutil.ReadDir(dirPath)
 
 			if err != nil {
-				return object.Error("fs.folders: " + err.Error())
+				return object.Error{Message: "fs.folders: " + err.Error()}
 			}
 
 			for _, file := range dir {
@@ -121,7 +121,7 @@ var stdFs = object.Record{
 			matches, err := filepath.Glob(pattern)
 
 			if err != nil {
-				return object.Error("fs.glob: " + err.Error())
+				return object.Error{Message: "fs.glob: " + err.Error()}
 			}
 
 			files := make([]object.Object, len(matches))
@@ -140,7 +140,7 @@ var stdFs = object.Record{
 			home, err := os.UserHomeDir()
 
 			if err != nil {
-				return object.Error("fs.home: " + err.Error())
+				return object.Error{Message: "fs.home: " + err.Error()}
 			}
 
 			return object.String(home)
@@ -153,7 +153,7 @@ var stdFs = object.Record{
 			home, err := os.UserConfigDir()
 
 			if err != nil {
-				return object.Error("fs.config: " + err.Error())
+				return object.Error{Message: "fs.config: " + err.Error()}
 			}
 
 			return object.String(home)
@@ -170,7 +170,7 @@ var stdFs = object.Record{
 			file, err := ioutil.ReadFile(args[1].String())
 
 			if err != nil {
-				return object.Error("fs.read: " + err.Error())
+				return object.Error{Message: "fs.read: " + err.Error()}
 			}
 
 			return object.String(file)
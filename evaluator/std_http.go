@@ -27,7 +27,7 @@ var stdHttp = object.Record{
 			resp, err := http.Get(url)
 
 			if err != nil {
-				return object.Error("http.get: " + err.Error())
+				return object.Error{Message: "http.get: " + err.Error()}
 			}
 
 			defer resp.Body.Close()
@@ -35,7 +35,7 @@ var stdHttp = object.Record{
 			body, err := ioutil.ReadAll(resp.Body)
 
 			if err != nil {
-				return object.Error("http.get: " + err.Error())
+				return object.Error{Message: "http.get: " + err.Error()}
 			}
 
 			return object.String(body)
@@ -8,27 +8,24 @@ var stdList = object.Record{
 	Stoned: true,
 	Values: map[string]object.Object{
 		"each": object.BuiltinFunction(func(args ...object.Object) object.Object {
-			if err := checkArgLength("list.map", args, 2); err != nil {
+			if err := checkArgLength("list.each", args, 2); err != nil {
 				return err
 			}
 
-			if err := checkFirstArgType("list.map", args[0], object.LIST); err != nil {
+			if err := checkFirstArgType("list.each", args[0], object.LIST); err != nil {
 				return err
 			}
 
-			if err := checkSecondArgType("list.map", args[1], object.FUNCTION); err != nil {
+			if err := checkSecondArgType("list.each", args[1], object.FUNCTION); err != nil {
 				return err
 			}
 
 			list := args[0].(object.List)
 
-			switch fun := args[1].(type) {
-			case object.BuiltinFunction:
-				for _, item := range list {
-					fun(item)
+			for _, item := range list {
+				if result := callFunction(args[1], item); isError(result) {
+					return result
 				}
-			case object.Function:
-
 			}
 
 			return object.Nil{}
@@ -49,13 +46,14 @@ var stdList = object.Record{
 			list := args[0].(object.List)
 			mappedList := make([]object.Object, len(list))
 
-			switch fun := args[1].(type) {
-			case object.BuiltinFunction:
-				for i, item := range list {
-					mappedList[i] = fun(item)
+			for i, item := range list {
+				result := callFunction(args[1], item)
+
+				if isError(result) {
+					return result
 				}
-			case object.Function:
 
+				mappedList[i] = result
 			}
 
 			return object.List(mappedList)
@@ -76,16 +74,16 @@ var stdList = object.Record{
 			list := args[0].(object.List)
 			filteredList := make([]object.Object, 0)
 
-			switch fun := args[1].(type) {
-			case object.BuiltinFunction:
-				for _, item := range list {
-					if fun(item).Bool() {
-						filteredList = append(filteredList, item)
+			for _, item := range list {
+				result := callFunction(args[1], item)
 
-					}
+				if isError(result) {
+					return result
 				}
-			case object.Function:
 
+				if result.Bool() {
+					filteredList = append(filteredList, item)
+				}
 			}
 
 			return object.List(filteredList)
@@ -104,15 +102,14 @@ var stdList = object.Record{
 			}
 
 			list := args[0].(object.List)
-			accumulator := args[1]
+			accumulator := args[2]
 
-			switch fun := args[1].(type) {
-			case object.BuiltinFunction:
-				for _, item := range list {
-					accumulator = fun(accumulator, item)
-				}
-			case object.Function:
+			for _, item := range list {
+				accumulator = callFunction(args[1], accumulator, item)
 
+				if isError(accumulator) {
+					return accumulator
+				}
 			}
 
 			return accumulator
@@ -20,7 +20,7 @@ var stdLog = object.Record{
 			f, err := os.OpenFile(filepath.Join(logFolder, "info.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 
 			if err != nil {
-				return object.Error("log.info: " + err.Error())
+				return object.Error{Message: "log.info: " + err.Error()}
 			}
 
 			_, _ = f.WriteString(args[0].String())
@@ -35,7 +35,7 @@ var stdLog = object.Record{
 			f, err := os.OpenFile(filepath.Join(logFolder, "error.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 
 			if err != nil {
-				return object.Error("log.error: " + err.Error())
+				return object.Error{Message: "log.error: " + err.Error()}
 			}
 
 			_, _ = f.WriteString(args[0].String())
@@ -0,0 +1,22 @@
+package evaluator
+
+import "../object"
+
+// SetArgs sets the arguments given to the script on the command line, the
+// script sees them as the list os.args
+func SetArgs(args []string) {
+	list := make(object.List, len(args))
+
+	for i, arg := range args {
+		list[i] = object.String(arg)
+	}
+
+	stdOs.Values["args"] = list
+}
+
+var stdOs = object.Record{
+	Stoned: true,
+	Values: map[string]object.Object{
+		"args": object.List{},
+	},
+}
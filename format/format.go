@@ -0,0 +1,226 @@
+// Package format prints programs in the one canonical style. Formatting is
+// idempotent and keeps comments, and lists, records and calls stay on one
+// line unless their first element was on a line of its own.
+package format
+
+import (
+	"strings"
+
+	"../ast"
+	"../lexer"
+	"../parser"
+	"../tokens"
+)
+
+// Source formats a whole program. A program with syntax errors can't be
+// formatted, the errors are returned instead.
+func Source(source string) (string, []parser.Error) {
+	lex := lexer.New(source)
+	lex.KeepTrivia()
+
+	pars := parser.New(lex)
+	program := pars.ParseProgram()
+
+	if pars.HasErrors() {
+		return "", pars.Errors()
+	}
+
+	p := printer{
+		source:     source,
+		trivia:     program.Trivia,
+		lines:      []string{""},
+		verbatim:   map[int]bool{},
+		blockStart: true,
+	}
+
+	for _, stmt := range program.Body.Statements {
+		p.statementLine(stmt)
+	}
+
+	p.newline()
+	p.flush(program.Body.End.Pos.Offset, true)
+
+	return p.String(), nil
+}
+
+type printer struct {
+	source string
+	trivia []tokens.Token // comments and blank lines not printed yet
+
+	lines    []string     // the last one is the line being written
+	verbatim map[int]bool // lines of multi-line strings, left exactly as they are
+	indent   int
+
+	blockStart bool // nothing has been written in the current block yet
+	inline     int  // > 0 while printing something that must stay on one line
+}
+
+func (p *printer) String() string {
+	for i, line := range p.lines {
+		if !p.verbatim[i] {
+			p.lines[i] = strings.TrimRight(line, " \t")
+		}
+	}
+
+	output := strings.Trim(strings.Join(p.lines, "\n"), "\n")
+
+	if output == "" {
+		return ""
+	}
+
+	return output + "\n"
+}
+
+// write adds text to the current line, indenting it if it's the first thing
+// on the line. Newlines in text are kept as is, they only come from raw
+// strings.
+func (p *printer) write(text string) {
+	last := len(p.lines) - 1
+
+	if p.lines[last] == "" {
+		p.lines[last] = strings.Repeat("\t", p.indent)
+	}
+
+	parts := strings.Split(text, "\n")
+	p.lines[last] += parts[0]
+
+	if len(parts) > 1 {
+		for i := range parts[:len(parts)-1] {
+			p.verbatim[last+i] = true
+		}
+		p.lines = append(p.lines, parts[1:]...)
+	}
+
+	p.blockStart = false
+}
+
+// newline starts a new line, in inline mode it's only a space
+func (p *printer) newline() {
+	if p.inline > 0 {
+		p.write(" ")
+		return
+	}
+
+	p.lines = append(p.lines, "")
+}
+
+// flush prints the comments and blank lines before offset. It's called at
+// the start of an empty line. Blank lines at the start of a block are dropped,
+// and so are the ones at the end if atEnd is set.
+func (p *printer) flush(offset int, atEnd bool) {
+	for len(p.trivia) > 0 && p.trivia[0].Pos.Offset < offset {
+		trivia := p.trivia[0]
+		p.trivia = p.trivia[1:]
+
+		switch {
+		case trivia.Type == tokens.BLANK_LINE:
+			if !p.blockStart && !(atEnd && p.onlyBlankLinesBefore(offset)) && p.lines[len(p.lines)-2] != "" {
+				p.newline()
+			}
+		case p.isTrailing(trivia):
+			// belongs at the end of the line just written
+			previous := len(p.lines) - 2
+			p.lines[previous] = strings.TrimRight(p.lines[previous], " \t") + " " + trivia.Literal
+		default:
+			p.write(trivia.Literal)
+			p.newline()
+		}
+	}
+}
+
+func (p *printer) onlyBlankLinesBefore(offset int) bool {
+	for _, trivia := range p.trivia {
+		if trivia.Pos.Offset >= offset {
+			break
+		}
+
+		if trivia.Type != tokens.BLANK_LINE {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasComment reports if a comment not printed yet lies between the offsets
+func (p *printer) hasComment(start int, end int) bool {
+	for _, trivia := range p.trivia {
+		if trivia.Pos.Offset >= end {
+			break
+		}
+
+		if trivia.Type == tokens.COMMENT && trivia.Pos.Offset > start {
+			return true
+		}
+	}
+
+	return false
+}
+
+// printerState is what printing changes, saved to try printing something
+// one way and go back if it doesn't work out
+type printerState struct {
+	lines      []string
+	verbatim   map[int]bool
+	trivia     []tokens.Token
+	blockStart bool
+}
+
+func (p *printer) save() printerState {
+	verbatim := make(map[int]bool, len(p.verbatim))
+
+	for line := range p.verbatim {
+		verbatim[line] = true
+	}
+
+	return printerState{
+		lines:      append([]string{}, p.lines...),
+		verbatim:   verbatim,
+		trivia:     p.trivia,
+		blockStart: p.blockStart,
+	}
+}
+
+func (p *printer) restore(state printerState) {
+	p.lines = state.lines
+	p.verbatim = state.verbatim
+	p.trivia = state.trivia
+	p.blockStart = state.blockStart
+}
+
+// isTrailing reports if a comment has code before it on its line
+func (p *printer) isTrailing(comment tokens.Token) bool {
+	start := strings.LastIndexByte(p.source[:comment.Pos.Offset], '\n') + 1
+	return strings.TrimSpace(p.source[start:comment.Pos.Offset]) != "" && len(p.lines) > 1
+}
+
+// statementLine prints a statement on a line of its own with the comments
+// before it
+func (p *printer) statementLine(stmt ast.Statement) {
+	p.newline()
+
+	if p.inline == 0 {
+		p.flush(stmt.StartPos().Offset, false)
+	}
+
+	p.statement(stmt)
+}
+
+// block prints the statements of a block one level in, the caller prints
+// whatever closes it
+func (p *printer) block(block ast.BlockStatement) {
+	p.indent++
+	p.blockStart = true
+
+	for _, stmt := range block.Statements {
+		p.statementLine(stmt)
+	}
+
+	p.newline()
+
+	if p.inline == 0 {
+		p.flush(block.End.Pos.Offset, true)
+	}
+
+	p.indent--
+}
@@ -0,0 +1,62 @@
+package format
+
+import (
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"a   =   1\nb=(1+2)*3-(4-5)\nc = - (a + b)\nd = not (a and b) or c\n",
+			"a = 1\nb = (1 + 2) * 3 - (4 - 5)\nc = -(a + b)\nd = not (a and b) or c\n",
+		},
+		{
+			"xs = [1,2,\n  3]\nys = [\n1,\n2]\nrec = {x = 1, \"y z\" = {inner = true},}\nf(a,\n b)\n",
+			"xs = [1, 2, 3]\nys = [\n\t1,\n\t2,\n]\nrec = {x = 1, \"y z\" = {inner = true}}\nf(a, b)\n",
+		},
+		{
+			"n = 0x1F + 1_000 + .5e3\ns = \"a\\tb {x + 1} \\{\"\nr = `raw  \ntext`\n",
+			"n = 0x1F + 1_000 + .5e3\ns = \"a\\tb {x + 1} \\{\"\nr = `raw  \ntext`\n",
+		},
+		{
+			"if a then b = 1 elseif c then\nb = 2 else b = 3 end\nloop break end\nf = func (x) return end\n",
+			"if a then\n\tb = 1\nelseif c then\n\tb = 2\nelse\n\tb = 3\nend\nloop\n\tbreak\nend\nf = func (x)\n\treturn\nend\n",
+		},
+		{
+			"print(\"{func () return 1 end()}\")\n",
+			"print(\"{func () return 1 end()}\")\n",
+		},
+		{
+			"\n\n# header\n\n\n\na = 1 # one\nf = func ()\n\n\t# inside\n\treturn 1 # result\n\n\t# at the end\n\nend\nxs = [\n\t1, # first\n\n\t# second\n\t2,\n]\n# done\n\n",
+			"# header\n\na = 1 # one\nf = func ()\n\t# inside\n\treturn 1 # result\n\n\t# at the end\nend\nxs = [\n\t1, # first\n\n\t# second\n\t2,\n]\n# done\n",
+		},
+		{
+			"x = [1, # one\n 2, # two\n 3]\ny = f(a, b # b\n)\nz = [f(func () # inside\nend), 2]\n",
+			"x = [\n\t1, # one\n\t2, # two\n\t3,\n]\ny = f(\n\ta,\n\tb, # b\n)\nz = [f(func () # inside\nend), 2]\n",
+		},
+		{"", ""},
+	}
+
+	for i, test := range tests {
+		output, errs := Source(test.input)
+
+		if errs != nil {
+			t.Fatalf("tests[%d] - unexpected errors: %v", i, errs)
+		}
+
+		if output != test.expected {
+			t.Fatalf("tests[%d] - expected:\n%v\ninstead we got:\n%v", i, test.expected, output)
+		}
+
+		if again, _ := Source(output); again != output {
+			t.Fatalf("tests[%d] - formatting isn't idempotent, got:\n%v", i, again)
+		}
+	}
+
+	if _, errs := Source("a = (1"); len(errs) != 1 {
+		t.Fatalf("expected a syntax error, got %v", errs)
+	}
+}
@@ -0,0 +1,230 @@
+package format
+
+import (
+	"strings"
+
+	"../ast"
+	"../parser"
+	"../tokens"
+)
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case ast.AssignmentStatement:
+		if stmt.Local {
+			p.write("local ")
+		}
+		p.expression(stmt.Target)
+		p.write(" = ")
+		p.expression(stmt.Value)
+	case ast.ShorthandAssignmentStatement:
+		p.expression(stmt.Target)
+		p.write(" " + stmt.Operator.String() + " ")
+		p.expression(stmt.Value)
+	case ast.ExpressionStatement:
+		p.expression(stmt.Expression)
+	case ast.ReturnStatement:
+		p.write("return")
+		if stmt.Value != nil {
+			p.write(" ")
+			p.expression(stmt.Value)
+		}
+	case ast.IfStatement:
+		p.ifStatement(stmt)
+	case ast.LoopStatement:
+		p.write("loop")
+		p.block(stmt.Body)
+		p.write("end")
+	case ast.BreakStatement:
+		p.write("break")
+	case ast.ContinueStatement:
+		p.write("continue")
+	}
+}
+
+func (p *printer) ifStatement(stmt ast.IfStatement) {
+	for i, condition := range stmt.Conditions {
+		// the parser turns else into a condition that is always true
+		if boolean, ok := condition.(ast.BooleanExpression); ok && boolean.Token.Type == tokens.ELSE {
+			p.write("else")
+		} else {
+			if i == 0 {
+				p.write("if ")
+			} else {
+				p.write("elseif ")
+			}
+			p.expression(condition)
+			p.write(" then")
+		}
+
+		p.block(stmt.Consequences[i])
+	}
+
+	p.write("end")
+}
+
+func (p *printer) expression(expr ast.Expression) {
+	switch expr := expr.(type) {
+	case ast.IdentifierExpression:
+		p.write(expr.Name)
+	case ast.NumberExpression:
+		p.literal(expr.Token, expr.String(0))
+	case ast.TextExpression:
+		p.literal(expr.Token, expr.String(0))
+	case ast.BooleanExpression:
+		p.write(expr.String(0))
+	case ast.InterpolationExpression:
+		p.interpolation(expr)
+	case ast.PrefixExpression:
+		if expr.Operator == tokens.NOT {
+			p.write("not ")
+		} else {
+			p.write(expr.Operator.String())
+		}
+		p.operand(expr.RightSide, parser.PREFIX)
+	case ast.InfixExpression:
+		precedence := parser.Precedence(expr.Operator)
+
+		// operators group to the left, so only a right side on the same
+		// level needs parentheses
+		p.operand(expr.LeftSide, precedence)
+		p.write(" " + expr.Operator.String() + " ")
+		p.operand(expr.RightSide, precedence+1)
+	case ast.DotExpression:
+		p.operand(expr.Left, parser.CALL)
+		p.write("." + expr.Name)
+	case ast.IndexExpression:
+		p.operand(expr.Left, parser.CALL)
+		p.write("[")
+		p.expression(expr.Index)
+		p.write("]")
+	case ast.CallExpression:
+		p.operand(expr.Function, parser.CALL)
+		p.list("(", ")", expr.Token, expr.End, expr.Arguments, nil)
+	case ast.ListExpression:
+		p.list("[", "]", expr.Token, expr.End, expr.Values, nil)
+	case ast.RecordExpression:
+		p.list("{", "}", expr.Token, expr.End, expr.Values, expr.Keys)
+	case ast.FunctionExpression:
+		p.write("func (" + strings.Join(expr.Parameters, ", ") + ")")
+		p.block(expr.Body)
+		p.write("end")
+	}
+}
+
+// operand prints an expression in parentheses if it binds looser than the
+// operator it belongs to
+func (p *printer) operand(expr ast.Expression, precedence int) {
+	if precedenceOf(expr) >= precedence {
+		p.expression(expr)
+		return
+	}
+
+	p.write("(")
+	p.expression(expr)
+	p.write(")")
+}
+
+func precedenceOf(expr ast.Expression) int {
+	switch expr := expr.(type) {
+	case ast.InfixExpression:
+		return parser.Precedence(expr.Operator)
+	case ast.PrefixExpression:
+		return parser.PREFIX
+	}
+	return parser.CALL + 1
+}
+
+// literal prints numbers and strings the way they were written
+func (p *printer) literal(token tokens.Token, canonical string) {
+	if token.Raw != "" {
+		p.write(token.Raw)
+	} else {
+		p.write(canonical)
+	}
+}
+
+func (p *printer) interpolation(expr ast.InterpolationExpression) {
+	// an interpolation can't span lines
+	p.inline++
+	p.write(`"`)
+
+	for _, part := range expr.Parts {
+		if text, ok := part.(ast.TextExpression); ok && text.Token.Type != tokens.STRING {
+			p.write(ast.Escape(text.Value))
+			continue
+		}
+
+		p.write("{")
+		p.expression(part)
+		p.write("}")
+	}
+
+	p.write(`"`)
+	p.inline--
+}
+
+// list prints the values between open and close, on one line unless the
+// first value was on a line of its own or there are comments between the
+// values. Records pass their keys.
+func (p *printer) list(open string, close string, start tokens.Token, end tokens.Token, values []ast.Expression, keys []string) {
+	multiline := p.inline == 0 && len(values) > 0 && values[0].StartPos().Line > start.Pos.Line
+
+	if !multiline && p.inline == 0 && p.hasComment(start.Pos.Offset, end.Pos.Offset) {
+		// comments inside the values are printed with them, only the ones
+		// left over after printing the list on one line are between values
+		saved := p.save()
+		p.values(open, close, end, values, keys, false)
+
+		if !p.hasComment(start.Pos.Offset, end.Pos.Offset) {
+			return
+		}
+
+		p.restore(saved)
+		multiline = true
+	}
+
+	p.values(open, close, end, values, keys, multiline)
+}
+
+func (p *printer) values(open string, close string, end tokens.Token, values []ast.Expression, keys []string, multiline bool) {
+	p.write(open)
+
+	if multiline {
+		p.indent++
+	}
+
+	for i, value := range values {
+		if multiline {
+			p.newline()
+			p.flush(value.StartPos().Offset, false)
+		} else if i > 0 {
+			p.write(", ")
+		}
+
+		if keys != nil {
+			p.write(recordKey(keys[i]) + " = ")
+		}
+
+		p.expression(value)
+
+		if multiline {
+			p.write(",")
+		}
+	}
+
+	if multiline {
+		p.newline()
+		p.flush(end.Pos.Offset, true)
+		p.indent--
+	}
+
+	p.write(close)
+}
+
+func recordKey(key string) string {
+	if tokens.IsIdentifier(key) {
+		return key
+	}
+	return ast.Quote(key)
+}
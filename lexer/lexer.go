@@ -1,6 +1,10 @@
 package lexer
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
@@ -10,33 +14,123 @@ import (
 const EOF = 0
 
 type Lexer struct {
-	input string
-
-	line int
-	col  int
-
-	current    rune
-	currentPos int
-	lastLen    int
+	reader *bufio.Reader
+	ahead  []char // characters peeked at but not read yet
+	done   bool   // the reader has nothing more to give
+	err    error
+
+	line   int
+	col    int
+	offset int        // in bytes
+	start  tokens.Pos // where the token being read starts
+
+	current     rune
+	currentSize int
+
+	// the source of the token being read, as far as it has been read
+	text      strings.Builder
+	recording bool
+
+	// brace depth inside each interpolated string being read, the string
+	// continues at a "}" when the depth is 0
+	interpolations []int
+
+	keepTrivia bool
+	leading    []tokens.Token // trivia for the next token
+	newlines   int            // since the last token or comment
 }
 
 func New(input string) *Lexer {
-	lexer := Lexer{input: input, currentPos: 0}
+	return NewReader(strings.NewReader(input))
+}
+
+// NewReader creates a lexer that reads the program from reader bit by bit as
+// tokens are asked for, so the whole program never has to be in memory.
+func NewReader(reader io.Reader) *Lexer {
+	// the start of the input counts as the end of a line
+	lexer := Lexer{reader: bufio.NewReader(reader), line: 1, newlines: 1}
 	return &lexer
 }
 
+// KeepTrivia makes the lexer hand out comments and blank lines as trivia on
+// the tokens, see tokens.Trivia. It has to be called before the first token
+// is read.
+func (lexer *Lexer) KeepTrivia() {
+	lexer.keepTrivia = true
+}
+
+// StartAt makes the lexer count lines from line instead of 1, for input that
+// follows earlier input like in the repl. It has to be called before the
+// first token is read.
+func (lexer *Lexer) StartAt(line int) {
+	lexer.line = line
+}
+
+// Err returns the error that stopped the lexer from reading the input, if
+// any. The lexer treats it as the end of the input.
+func (lexer *Lexer) Err() error {
+	return lexer.err
+}
+
 func (lexer *Lexer) NextToken() tokens.Token {
+	token := lexer.nextToken()
+	lexer.newlines = 0
+
+	if !lexer.keepTrivia {
+		return token
+	}
+
+	trailing := lexer.trailingComment()
+
+	if len(lexer.leading) > 0 || trailing != nil {
+		token.Trivia = &tokens.Trivia{Leading: lexer.leading, Trailing: trailing}
+		lexer.leading = nil
+	}
+
+	return token
+}
+
+func (lexer *Lexer) nextToken() tokens.Token {
+	lexer.recording = false
 	lexer.readChar()
 
 	for unicode.IsSpace(lexer.current) {
+		// strings end on the line they start on, interpolations included
+		if lexer.current == '\n' && len(lexer.interpolations) > 0 {
+			lexer.interpolations = nil
+			lexer.start = lexer.pos()
+			return lexer.tokenLiteral(tokens.ILLEGAL, "unterminated string")
+		}
+
+		if lexer.current == '\n' {
+			lexer.newlines++
+
+			// the line this newline ends had nothing on it
+			if lexer.newlines == 2 && lexer.keepTrivia {
+				lexer.start = tokens.Pos{Line: lexer.line, Col: 1, Offset: lexer.offset - lexer.col + 1}
+				lexer.leading = append(lexer.leading, lexer.token(tokens.BLANK_LINE))
+			}
+		}
+
 		lexer.readChar()
 	}
 
 	if lexer.current == '#' {
-		lexer.readLine()
-		return lexer.NextToken()
+		comment := lexer.readComment()
+		lexer.newlines = 0
+
+		if lexer.keepTrivia {
+			lexer.leading = append(lexer.leading, comment)
+		}
+
+		return lexer.nextToken()
 	}
 
+	lexer.start = lexer.pos()
+	lexer.text.Reset()
+	lexer.text.WriteRune(lexer.current)
+	lexer.recording = true
+
 	switch lexer.current {
 	case '=':
 		if lexer.peek() == '=' {
@@ -92,38 +186,89 @@ func (lexer *Lexer) NextToken() tokens.Token {
 	case ')':
 		return lexer.token(tokens.R_PAREN)
 	case '{':
+		if depth := len(lexer.interpolations); depth > 0 {
+			lexer.interpolations[depth-1]++
+		}
 		return lexer.token(tokens.L_BRACE)
 	case '}':
-		return lexer.token(tokens.R_BRACE)
+		depth := len(lexer.interpolations)
+
+		if depth == 0 || lexer.interpolations[depth-1] > 0 {
+			if depth > 0 {
+				lexer.interpolations[depth-1]--
+			}
+			return lexer.token(tokens.R_BRACE)
+		}
+
+		// the expression is done, back to the string
+		str, err, open := lexer.getString()
+
+		if !open {
+			lexer.interpolations = lexer.interpolations[:depth-1]
+		}
+
+		switch {
+		case err != "":
+			return lexer.tokenLiteral(tokens.ILLEGAL, err)
+		case open:
+			return lexer.tokenLiteral(tokens.STRING_MID, str)
+		default:
+			return lexer.tokenLiteral(tokens.STRING_END, str)
+		}
 	case '[':
 		return lexer.token(tokens.L_BRACKET)
 	case ']':
 		return lexer.token(tokens.R_BRACKET)
 	case '.':
-		return lexer.token(tokens.DOT)
+		if !isDigit(lexer.peek()) {
+			return lexer.token(tokens.DOT)
+		}
 	case '"':
-		str := lexer.getString()
+		str, err, open := lexer.getString()
+
+		if open {
+			lexer.interpolations = append(lexer.interpolations, 0)
+		}
+
+		switch {
+		case err != "":
+			return lexer.tokenLiteral(tokens.ILLEGAL, err)
+		case open:
+			return lexer.tokenLiteral(tokens.STRING_START, str)
+		default:
+			return lexer.tokenLiteral(tokens.STRING, str)
+		}
+	case '`':
+		if lexer.peek() == '`' && lexer.peekAt(2) == '`' {
+			lexer.readChar()
+			lexer.readChar()
+			str, ok := lexer.getRawString("```")
+			if !ok {
+				return lexer.tokenLiteral(tokens.ILLEGAL, "unterminated heredoc string")
+			}
+			return lexer.tokenLiteral(tokens.STRING, dedent(str))
+		}
+
+		str, ok := lexer.getRawString("`")
+		if !ok {
+			return lexer.tokenLiteral(tokens.ILLEGAL, "unterminated raw string")
+		}
 		return lexer.tokenLiteral(tokens.STRING, str)
 	case EOF:
 		return lexer.token(tokens.EOF)
 	}
 
 	// check for numbers
-	if unicode.IsDigit(lexer.current) {
-		// TODO: RETURN ERROR
-		number := lexer.getDigits()
-
-		if lexer.peek() == '.' {
-			lexer.readChar() // char is now "."
-			lexer.readChar() // char is first digit (TODO: CHECK)
-			number += "." + lexer.getDigits()
+	if isDigit(lexer.current) || lexer.current == '.' {
+		number, err := lexer.getNumber()
+		if err != "" {
+			return lexer.tokenLiteral(tokens.ILLEGAL, err)
 		}
-
 		return lexer.tokenLiteral(tokens.NUMBER, number)
 	}
 
 	// check for keywords/identifiers
-	if isLetter(lexer.current) {
+	if tokens.IsIdentifierStart(lexer.current) {
 		identifier := lexer.getIdentifier()
 		token := tokens.LookupIdentifier(identifier)
 
@@ -134,65 +279,288 @@ func (lexer *Lexer) NextToken() tokens.Token {
 		}
 	}
 
-	return lexer.tokenLiteral(tokens.ILLEGAL, string(lexer.current))
+	return lexer.tokenLiteral(tokens.ILLEGAL, fmt.Sprintf("unexpected character %q", lexer.current))
+}
+
+// char is a character and how many bytes it took up in the input
+type char struct {
+	value rune
+	size  int
 }
 
 func (lexer *Lexer) readChar() {
-	lexer.currentPos += lexer.lastLen
+	if lexer.current == '\n' {
+		lexer.line += 1
+		lexer.col = 1
+	} else {
+		lexer.col += 1
+	}
+
+	lexer.offset += lexer.currentSize
+	var next char
 
-	if lexer.currentPos >= len(lexer.input) {
-		lexer.current = EOF
-		return
+	if len(lexer.ahead) > 0 {
+		next = lexer.ahead[0]
+		lexer.ahead = lexer.ahead[1:]
+	} else {
+		next = lexer.readRune()
 	}
 
-	current, currentLen := utf8.DecodeRuneInString(lexer.input[lexer.currentPos:])
-	lexer.current = current
-	lexer.lastLen = currentLen
+	lexer.current = next.value
+	lexer.currentSize = next.size
 
-	if lexer.current == '\n' {
-		lexer.col = 0
-		lexer.line += 1
+	if lexer.recording && lexer.current != EOF {
+		lexer.text.WriteRune(lexer.current)
 	}
 }
 
+func (lexer *Lexer) pos() tokens.Pos {
+	return tokens.Pos{Line: lexer.line, Col: lexer.col, Offset: lexer.offset}
+}
+
 func (lexer *Lexer) peek() rune {
-	if lexer.currentPos+lexer.lastLen > len(lexer.input) {
-		return EOF
+	return lexer.peekAt(1)
+}
+
+// peekAt looks n characters ahead without moving
+func (lexer *Lexer) peekAt(n int) rune {
+	for len(lexer.ahead) < n {
+		lexer.ahead = append(lexer.ahead, lexer.readRune())
+	}
+
+	return lexer.ahead[n-1].value
+}
+
+func (lexer *Lexer) readRune() char {
+	if lexer.done {
+		return char{EOF, 0}
+	}
+
+	value, size, err := lexer.reader.ReadRune()
+
+	if err != nil {
+		if err != io.EOF {
+			lexer.err = err
+		}
+
+		lexer.done = true
+		return char{EOF, 0}
 	}
 
-	peek, _ := utf8.DecodeRuneInString(lexer.input[lexer.currentPos+lexer.lastLen:])
-	return peek
+	return char{value, size}
 }
 
-func (lexer *Lexer) getString() string {
-	lexer.readChar() // consume "
-	start := lexer.currentPos
+// getString reads a string up to the closing quote and decodes its escape
+// sequences. Strings can't span lines. If the string is broken err describes
+// why, and the rest of it is skipped. open is true if the string stopped at
+// the "{" of an interpolation instead.
+func (lexer *Lexer) getString() (str string, err string, open bool) {
+	var builder strings.Builder
 
-	for lexer.current != '"' {
+	for {
 		lexer.readChar()
+
+		switch lexer.current {
+		case '"':
+			return builder.String(), err, false
+		case '{':
+			return builder.String(), err, true
+		case '\n', EOF:
+			return "", "unterminated string", false
+		case '\\':
+			lexer.readChar()
+			escape := lexer.current
+
+			if escaped, ok := lexer.getEscape(); ok {
+				builder.WriteRune(escaped)
+			} else if err == "" {
+				err = fmt.Sprintf("invalid escape sequence in string: \\%c", escape)
+			}
+		default:
+			builder.WriteRune(lexer.current)
+		}
 	}
+}
+
+// getRawString reads everything up to the closing delimiter as is, newlines
+// included. The current character is the last one of the opening delimiter.
+func (lexer *Lexer) getRawString(delimiter string) (string, bool) {
+	var builder strings.Builder
+
+	for {
+		lexer.readChar()
+
+		if lexer.current == EOF {
+			return "", false
+		}
 
-	return lexer.input[start:lexer.currentPos]
+		if lexer.atDelimiter(delimiter) {
+			for i := 1; i < len(delimiter); i++ {
+				lexer.readChar()
+			}
+
+			return builder.String(), true
+		}
+
+		builder.WriteRune(lexer.current)
+	}
 }
 
-func (lexer *Lexer) getIdentifier() string {
-	start := lexer.currentPos
+// atDelimiter reports if the current character starts the delimiter
+func (lexer *Lexer) atDelimiter(delimiter string) bool {
+	for i, char := range []rune(delimiter) {
+		if i == 0 && lexer.current != char || i > 0 && lexer.peekAt(i) != char {
+			return false
+		}
+	}
+
+	return true
+}
+
+// dedent turns the body of a heredoc into the string it stands for. The lines
+// holding the delimiters are dropped if they are otherwise empty, and the
+// indentation all lines have in common is removed, so
+//
+//	query = ```
+//		select *
+//		from users
+//	```
+//
+// is "select *\nfrom users".
+func dedent(str string) string {
+	lines := strings.Split(str, "\n")
+
+	if len(lines) > 1 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+
+	if len(lines) > 1 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	indent := ""
+	found := false
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+		if !found {
+			indent = lineIndent
+			found = true
+			continue
+		}
+
+		for !strings.HasPrefix(lineIndent, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+		} else {
+			lines[i] = line[len(indent):]
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+var escapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'"':  '"',
+	'{':  '{',
+	'\\': '\\',
+}
+
+// getEscape decodes the escape sequence starting at the current character,
+// the one after the backslash
+func (lexer *Lexer) getEscape() (rune, bool) {
+	if escaped, ok := escapes[lexer.current]; ok {
+		return escaped, true
+	}
+
+	// unicode code points are written as \u{1F600}
+	if lexer.current != 'u' || lexer.peek() != '{' {
+		return 0, false
+	}
+
+	lexer.readChar() // u -> {
+	var code rune
+
+	for digits := 0; ; digits++ {
+		value := hexValue(lexer.peek())
+
+		if value < 0 {
+			break
+		}
+
+		if digits == 6 {
+			return 0, false
+		}
+
+		lexer.readChar()
+		code = code*16 + value
+	}
+
+	if lexer.peek() != '}' || lexer.current == '{' || !utf8.ValidRune(code) {
+		return 0, false
+	}
+
+	lexer.readChar() // digit -> }
+	return code, true
+}
 
-	for isLetter(lexer.peek()) {
+func (lexer *Lexer) getIdentifier() string {
+	for tokens.IsIdentifierPart(lexer.peek()) {
 		lexer.readChar()
 	}
 
-	return lexer.input[start : lexer.currentPos+lexer.lastLen]
+	return lexer.text.String()
+}
+
+// readComment reads the comment starting at the current "#"
+func (lexer *Lexer) readComment() tokens.Token {
+	lexer.start = lexer.pos()
+	lexer.text.Reset()
+	lexer.text.WriteRune(lexer.current)
+	lexer.recording = true
+
+	lexer.readLine()
+	lexer.recording = false
+
+	// a \r before the newline belongs to the line ending, not the comment
+	text := strings.TrimRight(lexer.text.String(), "\r")
+	token := lexer.tokenLiteral(tokens.COMMENT, text)
+	token.EndOffset = token.Pos.Offset + len(text)
+	return token
 }
 
-func (lexer *Lexer) getDigits() string {
-	start := lexer.currentPos
+// trailingComment reads a comment that follows the token just read on the
+// same line, if there is one
+func (lexer *Lexer) trailingComment() *tokens.Token {
+	if lexer.current == '\n' || lexer.current == EOF {
+		return nil
+	}
 
-	for isDigit(lexer.peek()) {
+	for lexer.peek() == ' ' || lexer.peek() == '\t' {
 		lexer.readChar()
 	}
 
-	return lexer.input[start : lexer.currentPos+lexer.lastLen]
+	if lexer.peek() != '#' {
+		return nil
+	}
+
+	lexer.readChar()
+	comment := lexer.readComment()
+	return &comment
 }
 
 func (lexer *Lexer) readLine() {
@@ -202,23 +570,36 @@ func (lexer *Lexer) readLine() {
 }
 
 func (lexer *Lexer) token(tokenType tokens.TokenType) tokens.Token {
-	return tokens.Token{
-		Type:    tokenType,
-		Literal: "",
-		Pos:     tokens.Pos{Line: lexer.line, Col: lexer.col},
-	}
+	return lexer.tokenLiteral(tokenType, "")
 }
 
+// tokenLiteral makes a token from the start of the token being read up to and
+// including the current character
 func (lexer *Lexer) tokenLiteral(tokenType tokens.TokenType, literal string) tokens.Token {
-	return tokens.Token{
-		Type:    tokenType,
-		Literal: literal,
-		Pos:     tokens.Pos{Line: lexer.line, Col: lexer.col},
+	token := tokens.Token{
+		Type:      tokenType,
+		Literal:   literal,
+		Pos:       lexer.start,
+		EndOffset: lexer.offset + lexer.currentSize,
 	}
+
+	if tokenType == tokens.NUMBER || tokenType == tokens.STRING {
+		token.Raw = lexer.text.String()
+	}
+
+	return token
 }
 
-func isLetter(char rune) bool {
-	return 'a' <= char && char <= 'z' || char == '_'
+func hexValue(char rune) rune {
+	switch {
+	case '0' <= char && char <= '9':
+		return char - '0'
+	case 'a' <= char && char <= 'f':
+		return char - 'a' + 10
+	case 'A' <= char && char <= 'F':
+		return char - 'A' + 10
+	}
+	return -1
 }
 
 func isDigit(char rune) bool {
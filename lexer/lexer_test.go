@@ -1,7 +1,11 @@
 package lexer
 
 import (
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"../tokens"
 )
@@ -16,7 +20,7 @@ func TestNextToken(t *testing.T) {
 		, .
 		([{}])
 		not and or loop break continue func if then elseif
-		else return end true false nil
+		else return end local true false nil
 		# hello world + 5
 		"åäö" "öäå"
 		a.b
@@ -67,6 +71,7 @@ func TestNextToken(t *testing.T) {
 		{tokens.ELSE, ""},
 		{tokens.RETURN, ""},
 		{tokens.END, ""},
+		{tokens.LOCAL, ""},
 		{tokens.TRUE, ""},
 		{tokens.FALSE, ""},
 		{tokens.NIL, ""},
@@ -95,3 +100,395 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "a = 1\n  b += \"åä\" c\n# comment\n\td"
+
+	tests := []struct {
+		expectedType tokens.TokenType
+		expectedPos  tokens.Pos
+	}{
+		{tokens.IDENT, tokens.Pos{Line: 1, Col: 1, Offset: 0}},
+		{tokens.ASSIGN, tokens.Pos{Line: 1, Col: 3, Offset: 2}},
+		{tokens.NUMBER, tokens.Pos{Line: 1, Col: 5, Offset: 4}},
+		{tokens.IDENT, tokens.Pos{Line: 2, Col: 3, Offset: 8}},
+		{tokens.ADD_ASSIGN, tokens.Pos{Line: 2, Col: 5, Offset: 10}},
+		{tokens.STRING, tokens.Pos{Line: 2, Col: 8, Offset: 13}},
+		{tokens.IDENT, tokens.Pos{Line: 2, Col: 13, Offset: 20}},
+		{tokens.IDENT, tokens.Pos{Line: 4, Col: 2, Offset: 33}},
+	}
+	l := New(input)
+
+	for i, token := range tests {
+		tok := l.NextToken()
+		if tok.Type != token.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, token.expectedType, tok.Type)
+		}
+		if tok.Pos != token.expectedPos {
+			t.Fatalf("tests[%d] - position wrong. expected=%v, got=%v",
+				i, token.expectedPos, tok.Pos)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    tokens.TokenType
+		expectedLiteral string
+	}{
+		{`"a\nb\tc\r"`, tokens.STRING, "a\nb\tc\r"},
+		{`"say \"hi\" \\ bye"`, tokens.STRING, `say "hi" \ bye`},
+		{`"\u{48}\u{e5}\u{1F600}"`, tokens.STRING, "Hå😀"},
+		{`"\0"`, tokens.STRING, "\x00"},
+		{`"\q"`, tokens.ILLEGAL, `invalid escape sequence in string: \q`},
+		{`"\u{}"`, tokens.ILLEGAL, `invalid escape sequence in string: \u`},
+		{`"\u{110000}"`, tokens.ILLEGAL, `invalid escape sequence in string: \u`},
+		{`"\u{1234567}"`, tokens.ILLEGAL, `invalid escape sequence in string: \u`},
+		{`"abc`, tokens.ILLEGAL, "unterminated string"},
+		{"\"abc\nd\"", tokens.ILLEGAL, "unterminated string"},
+		{`"abc\"`, tokens.ILLEGAL, "unterminated string"},
+		{"$", tokens.ILLEGAL, `unexpected character '$'`},
+	}
+
+	for i, test := range tests {
+		tok := New(test.input).NextToken()
+
+		if tok.Type != test.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, test.expectedType, tok.Type)
+		}
+		if tok.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, test.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// the lexer carries on after a broken string
+	l := New("x = \"abc\ny")
+	expected := []struct {
+		expectedType tokens.TokenType
+		expectedPos  tokens.Pos
+	}{
+		{tokens.IDENT, tokens.Pos{Line: 1, Col: 1, Offset: 0}},
+		{tokens.ASSIGN, tokens.Pos{Line: 1, Col: 3, Offset: 2}},
+		{tokens.ILLEGAL, tokens.Pos{Line: 1, Col: 5, Offset: 4}},
+		{tokens.IDENT, tokens.Pos{Line: 2, Col: 1, Offset: 9}},
+		{tokens.EOF, tokens.Pos{Line: 2, Col: 2, Offset: 10}},
+	}
+
+	for i, token := range expected {
+		tok := l.NextToken()
+		if tok.Type != token.expectedType || tok.Pos != token.expectedPos {
+			t.Fatalf("expected[%d] - expected %q at %v, got %q at %v",
+				i, token.expectedType, token.expectedPos, tok.Type, tok.Pos)
+		}
+	}
+}
+
+func TestRawStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    tokens.TokenType
+		expectedLiteral string
+	}{
+		{"``", tokens.STRING, ""},
+		{"`C:\\new\\table`", tokens.STRING, `C:\new\table`},
+		{"`a \"quoted\"\n  b`", tokens.STRING, "a \"quoted\"\n  b"},
+		{"`abc", tokens.ILLEGAL, "unterminated raw string"},
+		{"``````", tokens.STRING, ""},
+		{"```one line```", tokens.STRING, "one line"},
+		{"```\n\t\tselect *\n\t\t  from users\n\n\t\twhere id = 1\n\t```", tokens.STRING,
+			"select *\n  from users\n\nwhere id = 1"},
+		{"```\n    a\n  b\n```", tokens.STRING, "  a\nb"},
+		{"```\n\tno `escapes` \\n here\n```", tokens.STRING, "no `escapes` \\n here"},
+		{"```\nabc\n``", tokens.ILLEGAL, "unterminated heredoc string"},
+	}
+
+	for i, test := range tests {
+		tok := New(test.input).NextToken()
+
+		if tok.Type != test.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, test.expectedType, tok.Type)
+		}
+		if tok.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, test.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// positions after a multi-line string are still right
+	l := New("a = `x\ny` b\n```\nz\n``` c")
+	expected := []tokens.Pos{
+		{Line: 1, Col: 1, Offset: 0},
+		{Line: 1, Col: 3, Offset: 2},
+		{Line: 1, Col: 5, Offset: 4},
+		{Line: 2, Col: 4, Offset: 10},
+		{Line: 3, Col: 1, Offset: 12},
+		{Line: 5, Col: 5, Offset: 22},
+	}
+
+	for i, pos := range expected {
+		if tok := l.NextToken(); tok.Pos != pos {
+			t.Fatalf("expected[%d] - position wrong. expected=%v, got=%v", i, pos, tok.Pos)
+		}
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	input := `"hi {name}, {count + 1} items in {rec["x"]} {"a{b}"}" {} "\{x}"`
+
+	tests := []struct {
+		expectedType    tokens.TokenType
+		expectedLiteral string
+	}{
+		{tokens.STRING_START, "hi "},
+		{tokens.IDENT, "name"},
+		{tokens.STRING_MID, ", "},
+		{tokens.IDENT, "count"},
+		{tokens.ADD, ""},
+		{tokens.NUMBER, "1"},
+		{tokens.STRING_MID, " items in "},
+		{tokens.IDENT, "rec"},
+		{tokens.L_BRACKET, ""},
+		{tokens.STRING, "x"},
+		{tokens.R_BRACKET, ""},
+		{tokens.STRING_MID, " "},
+		{tokens.STRING_START, "a"},
+		{tokens.IDENT, "b"},
+		{tokens.STRING_END, ""},
+		{tokens.STRING_END, ""},
+		{tokens.L_BRACE, ""},
+		{tokens.R_BRACE, ""},
+		{tokens.STRING, "{x}"},
+		{tokens.EOF, ""},
+	}
+	l := New(input)
+
+	for i, token := range tests {
+		tok := l.NextToken()
+		if tok.Type != token.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, token.expectedType, tok.Type)
+		}
+		if tok.Literal != token.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, token.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    tokens.TokenType
+		expectedLiteral string
+	}{
+		{"0", tokens.NUMBER, "0"},
+		{"123.456", tokens.NUMBER, "123.456"},
+		{".5", tokens.NUMBER, ".5"},
+		{"1_000_000", tokens.NUMBER, "1_000_000"},
+		{"1e-9", tokens.NUMBER, "1e-9"},
+		{"2.5E+10", tokens.NUMBER, "2.5E+10"},
+		{"0x1F", tokens.NUMBER, "0x1F"},
+		{"0Xdead_beef", tokens.NUMBER, "0Xdead_beef"},
+		{"0b1010", tokens.NUMBER, "0b1010"},
+		{"0o755", tokens.NUMBER, "0o755"},
+		{"1.2.3", tokens.ILLEGAL, `unexpected "." in number`},
+		{"0xZZ", tokens.ILLEGAL, "hexadecimal number has no digits"},
+		{"0x1G", tokens.ILLEGAL, "invalid character 'G' in hexadecimal number"},
+		{"0b102", tokens.ILLEGAL, "invalid character '2' in binary number"},
+		{"1.", tokens.ILLEGAL, "expected digits after the decimal point"},
+		{"1e", tokens.ILLEGAL, "exponent has no digits"},
+		{"12abc", tokens.ILLEGAL, "invalid character 'a' in number"},
+		{"1__0", tokens.ILLEGAL, `"_" must separate digits in a number`},
+		{"1_", tokens.ILLEGAL, `"_" must separate digits in a number`},
+		{"0x_1", tokens.ILLEGAL, `"_" must separate digits in a number`},
+		{"0755", tokens.ILLEGAL, "use 0o for octal numbers"},
+		{"00", tokens.ILLEGAL, "use 0o for octal numbers"},
+		{"0_1", tokens.ILLEGAL, "use 0o for octal numbers"},
+		{"0.5", tokens.NUMBER, "0.5"},
+		{"0e3", tokens.NUMBER, "0e3"},
+	}
+
+	for i, test := range tests {
+		tok := New(test.input).NextToken()
+
+		if tok.Type != test.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, test.expectedType, tok.Type)
+		}
+		if tok.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, test.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// a malformed number is skipped as a whole
+	l := New("a = 1.2.3.4 + xs.b")
+	expected := []tokens.TokenType{
+		tokens.IDENT, tokens.ASSIGN, tokens.ILLEGAL, tokens.ADD, tokens.IDENT, tokens.DOT, tokens.IDENT, tokens.EOF,
+	}
+
+	for i, tokenType := range expected {
+		if tok := l.NextToken(); tok.Type != tokenType {
+			t.Fatalf("expected[%d] - tokentype wrong. expected=%q, got=%q", i, tokenType, tok.Type)
+		}
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	input := "myVar HTTP_TIMEOUT x1 _private größe 名前 End IF end x٣ ٣"
+
+	tests := []struct {
+		expectedType    tokens.TokenType
+		expectedLiteral string
+	}{
+		{tokens.IDENT, "myVar"},
+		{tokens.IDENT, "HTTP_TIMEOUT"},
+		{tokens.IDENT, "x1"},
+		{tokens.IDENT, "_private"},
+		{tokens.IDENT, "größe"},
+		{tokens.IDENT, "名前"},
+		{tokens.IDENT, "End"},
+		{tokens.IDENT, "IF"},
+		{tokens.END, ""},
+		{tokens.IDENT, "x٣"},
+		{tokens.ILLEGAL, "unexpected character '٣'"},
+		{tokens.EOF, ""},
+	}
+	l := New(input)
+
+	for i, token := range tests {
+		tok := l.NextToken()
+		if tok.Type != token.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, token.expectedType, tok.Type)
+		}
+		if tok.Literal != token.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, token.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestShebang(t *testing.T) {
+	l := New("#!/usr/bin/env monkey\nprint(1)")
+	l.KeepTrivia()
+
+	tok := l.NextToken()
+	if tok.Type != tokens.IDENT || tok.Pos != (tokens.Pos{Line: 2, Col: 1, Offset: 22}) {
+		t.Fatalf("expected the shebang line to be skipped, got %v at %v", tok, tok.Pos)
+	}
+
+	// the formatter keeps it since it's a comment like any other
+	expected := tokens.Token{Type: tokens.COMMENT, Literal: "#!/usr/bin/env monkey", Pos: tokens.Pos{Line: 1, Col: 1}, EndOffset: 21}
+	if tok.Trivia == nil || len(tok.Trivia.Leading) != 1 || tok.Trivia.Leading[0] != expected {
+		t.Fatalf("expected the shebang line as leading trivia, got %v", tok.Trivia)
+	}
+}
+
+func TestReader(t *testing.T) {
+	input := "name = \"åäö {x}\" # kommentar\n" +
+		"größe = 0x1F + .5e3\n" +
+		"q = ```\n\tselect 😀\n```\n" +
+		"r = `raw\n名前` end"
+
+	// reading one byte at a time splits every multi-byte character
+	expected := New(input)
+	reader := NewReader(iotest.OneByteReader(strings.NewReader(input)))
+
+	for i := 0; ; i++ {
+		want := expected.NextToken()
+		got := reader.NextToken()
+
+		if got != want {
+			t.Fatalf("token %d - expected %+v, got %+v", i, want, got)
+		}
+
+		if want.Type == tokens.EOF {
+			break
+		}
+	}
+
+	// a read error ends the input and is kept around
+	failing := io.MultiReader(strings.NewReader("a = 1"), iotest.ErrReader(errors.New("disk on fire")))
+	l := NewReader(failing)
+
+	for tok := l.NextToken(); tok.Type != tokens.EOF; tok = l.NextToken() {
+	}
+
+	if l.Err() == nil || l.Err().Error() != "disk on fire" {
+		t.Fatalf("expected the read error to be kept, got %v", l.Err())
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	input := "# header\n\n\na = 1 # one\n\n  # about b\nb = 2\t#two\r\n# the end"
+	l := New(input)
+	l.KeepTrivia()
+
+	type trivia struct {
+		leading  []tokens.Token
+		trailing *tokens.Token
+	}
+
+	comment := func(text string, line, col, offset int) tokens.Token {
+		return tokens.Token{Type: tokens.COMMENT, Literal: text, Pos: tokens.Pos{Line: line, Col: col, Offset: offset}, EndOffset: offset + len(text)}
+	}
+	blank := func(line, offset int) tokens.Token {
+		return tokens.Token{Type: tokens.BLANK_LINE, Pos: tokens.Pos{Line: line, Col: 1, Offset: offset}, EndOffset: offset + 1}
+	}
+	one := comment("# one", 4, 7, 17)
+	two := comment("#two", 7, 7, 42)
+
+	tests := []struct {
+		expectedType tokens.TokenType
+		expected     trivia
+	}{
+		{tokens.IDENT, trivia{leading: []tokens.Token{comment("# header", 1, 1, 0), blank(2, 9)}}},
+		{tokens.ASSIGN, trivia{}},
+		{tokens.NUMBER, trivia{trailing: &one}},
+		{tokens.IDENT, trivia{leading: []tokens.Token{blank(5, 23), comment("# about b", 6, 3, 26)}}},
+		{tokens.ASSIGN, trivia{}},
+		{tokens.NUMBER, trivia{trailing: &two}},
+		{tokens.EOF, trivia{leading: []tokens.Token{comment("# the end", 8, 1, 48)}}},
+	}
+
+	for i, test := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != test.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, test.expectedType, tok.Type)
+		}
+
+		if tok.Trivia == nil {
+			if test.expected.leading != nil || test.expected.trailing != nil {
+				t.Fatalf("tests[%d] - expected trivia, got none", i)
+			}
+			continue
+		}
+
+		if len(tok.Trivia.Leading) != len(test.expected.leading) {
+			t.Fatalf("tests[%d] - expected leading %+v, got %+v", i, test.expected.leading, tok.Trivia.Leading)
+		}
+
+		for j, leading := range tok.Trivia.Leading {
+			if leading != test.expected.leading[j] {
+				t.Fatalf("tests[%d] - expected leading %+v, got %+v", i, test.expected.leading[j], leading)
+			}
+		}
+
+		if (tok.Trivia.Trailing == nil) != (test.expected.trailing == nil) ||
+			tok.Trivia.Trailing != nil && *tok.Trivia.Trailing != *test.expected.trailing {
+			t.Fatalf("tests[%d] - expected trailing %+v, got %+v", i, test.expected.trailing, tok.Trivia.Trailing)
+		}
+	}
+
+	// without asking for it there is no trivia
+	if tok := New(input).NextToken(); tok.Trivia != nil {
+		t.Fatalf("expected no trivia, got %+v", tok.Trivia)
+	}
+}
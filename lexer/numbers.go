@@ -0,0 +1,160 @@
+package lexer
+
+import (
+	"fmt"
+	"unicode"
+)
+
+type numberBase struct {
+	base rune
+	name string
+}
+
+var numberBases = map[rune]numberBase{
+	'x': {16, "hexadecimal number"},
+	'o': {8, "octal number"},
+	'b': {2, "binary number"},
+}
+
+// getNumber reads a number literal as it's written, like 0x1F, 1_000 or
+// .5e-3, the parser turns it into a value. A malformed number is skipped as a
+// whole and err describes what's wrong with it.
+func (lexer *Lexer) getNumber() (number string, err string) {
+	if err := lexer.readNumber(); err != "" {
+		lexer.skipNumber()
+		return "", err
+	}
+
+	return lexer.text.String(), ""
+}
+
+// readNumber moves to the last character of the number, the current one is
+// its first digit or a leading "."
+func (lexer *Lexer) readNumber() string {
+	if lexer.current == '0' {
+		if base, ok := numberBases[unicode.ToLower(lexer.peek())]; ok {
+			lexer.readChar() // 0 -> x
+
+			digits, err := lexer.readDigits(base.base)
+
+			if err != "" {
+				return err
+			}
+
+			if digits == 0 {
+				return fmt.Sprintf("%v has no digits", base.name)
+			}
+
+			return lexer.checkNumberEnd(base.name)
+		}
+	}
+
+	if lexer.current != '.' {
+		leadingZero := lexer.current == '0'
+		digits, err := lexer.readDigits(10)
+
+		if err != "" {
+			return err
+		}
+
+		// 0755 is octal in C and JavaScript, rather than quietly reading it
+		// as 755 it has to be written the one way that can't be mistaken
+		if next := unicode.ToLower(lexer.peek()); leadingZero && digits > 0 && next != '.' && next != 'e' {
+			return "use 0o for octal numbers"
+		}
+
+		if lexer.peek() == '.' {
+			lexer.readChar()
+		}
+	}
+
+	if lexer.current == '.' {
+		digits, err := lexer.readDigits(10)
+
+		if err != "" {
+			return err
+		}
+
+		if digits == 0 {
+			return "expected digits after the decimal point"
+		}
+	}
+
+	if unicode.ToLower(lexer.peek()) == 'e' {
+		lexer.readChar()
+
+		if sign := lexer.peek(); sign == '+' || sign == '-' {
+			lexer.readChar()
+		}
+
+		digits, err := lexer.readDigits(10)
+
+		if err != "" {
+			return err
+		}
+
+		if digits == 0 {
+			return "exponent has no digits"
+		}
+	}
+
+	return lexer.checkNumberEnd("number")
+}
+
+// readDigits reads digits in the given base after the current character.
+// Digits can be grouped with underscores, as in 1_000, but only between them.
+func (lexer *Lexer) readDigits(base rune) (digits int, err string) {
+	for {
+		peek := lexer.peek()
+
+		if peek == '_' {
+			if !isDigitIn(lexer.current, base) || !isDigitIn(lexer.peekAt(2), base) {
+				return digits, `"_" must separate digits in a number`
+			}
+
+			lexer.readChar()
+			continue
+		}
+
+		if !isDigitIn(peek, base) {
+			return digits, ""
+		}
+
+		lexer.readChar()
+		digits++
+	}
+}
+
+// checkNumberEnd makes sure a number isn't followed by something that looks
+// like more of it, as in 1.2.3 or 0xZZ
+func (lexer *Lexer) checkNumberEnd(name string) string {
+	peek := lexer.peek()
+
+	if peek == '.' && unicode.IsDigit(lexer.peekAt(2)) {
+		return fmt.Sprintf("unexpected \".\" in %v", name)
+	}
+
+	if peek == '_' || unicode.IsLetter(peek) || unicode.IsDigit(peek) {
+		return fmt.Sprintf("invalid character %q in %v", peek, name)
+	}
+
+	return ""
+}
+
+// skipNumber skips the rest of a malformed number
+func (lexer *Lexer) skipNumber() {
+	for {
+		peek := lexer.peek()
+
+		if peek != '_' && peek != '.' && !unicode.IsLetter(peek) && !unicode.IsDigit(peek) {
+			return
+		}
+
+		lexer.readChar()
+	}
+}
+
+func isDigitIn(char rune, base rune) bool {
+	value := hexValue(char)
+	return value >= 0 && value < base
+}
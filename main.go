@@ -1,56 +1,118 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
 
-	"./lexer"
-	"./parser"
+	"./repl"
 )
 
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  monkey [flags] script.mk [args...]  run a script
+  monkey [flags] -e code [args...]    run code given on the command line
+  monkey [flags] - [args...]          run the script on stdin
+  monkey [flags]                      start the repl
+  monkey fmt [--check] [files...]     format scripts
+
+flags:`)
+	flag.PrintDefaults()
+}
+
 func main() {
-	//const (
-	//	RLPL = iota // lex
-	//	RPPL        // parse
-	//	REPL        // evalutate
-	//)
-
-	//replType := REPL
-
-	//var input io.Reader
-	//
-	//if len(os.Args) >= 2 {
-	//	input, _ = os.Open(os.Args[1])
-	//} else {
-	//	input = os.Stdin
-	//}
-	//
-	//file, _ := ioutil.ReadFile(os.Args[1])
-	//
-	//repl.Rlpl(os.Stdin)
-	lex := lexer.New(`
-	`)
-	//
-	//for token := lex.NextToken(); token.Type != tokens.EOF; token = lex.NextToken() {
-	//	fmt.Println(token)
-	//}
-	//
-	pars := parser.New(lex)
-	program := pars.ParseProgram()
-	//_ = program
-	//
-	if pars.HasErrors() {
-		pars.PrintErrors()
-	} else {
-		fmt.Println(program.String(0))
-	}
-
-	//if replType == RLPL {
-	//	repl.Rlpl()
-	//} else if replType == RPPL {
-	//	fmt.Println("TODO")
-	//} else {
-	//	repl.Repl()
-	//}
-	//f, _ := filepath.Glob("/home/jesper/Pictures/.downloads/*/*")
-	//fmt.Println(strings.Join(f, "\n"))
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		os.Exit(formatCommand(os.Args[2:]))
+	}
+
+	flag.Usage = usage
+	opts, err := parseFlags(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		os.Exit(2)
+	}
+
+	if opts.code == "" && opts.filename == "" {
+		repl.Repl(os.Stdin, os.Stdout, opts.mode)
+		return
+	}
+
+	os.Exit(runCommand(opts.filename, opts.code, opts.args, opts.mode, opts.tokenFormat))
+}
+
+// options are what the command line asks for, without a filename or code
+// the repl is started
+type options struct {
+	mode        repl.Mode
+	tokenFormat string
+	code        string
+	filename    string
+	args        []string
+}
+
+// parseFlags reads the flags in arguments into flags. Bad flags are reported
+// on the output of flags together with its usage, like the flag package
+// does itself.
+func parseFlags(flags *flag.FlagSet, arguments []string) (options, error) {
+	modeName := flags.String("mode", "eval", "what to do with the input: print its `tokens` or ast, or eval it")
+	tokenFormat := flags.String("tokens", "", "print the tokens of the script as `json` or text, short for --mode=tokens")
+	code := flags.String("e", "", "run `code` instead of a script file")
+
+	if err := flags.Parse(arguments); err != nil {
+		return options{}, err
+	}
+
+	fail := func(err error) (options, error) {
+		fmt.Fprintln(flags.Output(), err)
+		flags.Usage()
+		return options{}, err
+	}
+
+	mode, err := repl.ParseMode(*modeName)
+	if err != nil {
+		return fail(err)
+	}
+
+	opts := options{mode: mode, tokenFormat: "text", code: *code, args: flags.Args()}
+
+	switch *tokenFormat {
+	case "":
+	case "json", "text":
+		opts.mode = repl.TOKENS
+		opts.tokenFormat = *tokenFormat
+	default:
+		// --tokens takes a value, so "--tokens script.mk" ends up here
+		return fail(fmt.Errorf("unknown token format %q for --tokens, expected json or text", *tokenFormat))
+	}
+
+	switch {
+	case opts.code != "":
+		opts.filename = "<eval>"
+	case len(opts.args) > 0:
+		// flags stop at the script, everything after it belongs to the script
+		opts.filename, opts.args = opts.args[0], opts.args[1:]
+	}
+
+	// the repl only prints tokens as text
+	if *tokenFormat != "" && opts.filename == "" {
+		return fail(errors.New("--tokens needs a script or -e code, use --mode=tokens for the repl"))
+	}
+
+	return opts, nil
+}
+
+// openScript opens the script to run, code from -e if it's given, otherwise
+// the file or stdin for "-"
+func openScript(filename string, code string) (io.ReadCloser, error) {
+	switch {
+	case code != "":
+		return ioutil.NopCloser(strings.NewReader(code)), nil
+	case filename == "-":
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+
+	return os.Open(filename)
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"./repl"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		arguments []string
+		expected  options
+	}{
+		{nil, options{mode: repl.EVAL, tokenFormat: "text"}},
+		{[]string{"script.mk", "--mode", "ast"}, options{mode: repl.EVAL, tokenFormat: "text", filename: "script.mk", args: []string{"--mode", "ast"}}},
+		{[]string{"--mode", "ast", "-"}, options{mode: repl.AST, tokenFormat: "text", filename: "-", args: []string{}}},
+		{[]string{"--tokens", "json", "script.mk"}, options{mode: repl.TOKENS, tokenFormat: "json", filename: "script.mk", args: []string{}}},
+		{[]string{"--tokens=text", "-e", "x = 1", "a"}, options{mode: repl.TOKENS, tokenFormat: "text", code: "x = 1", filename: "<eval>", args: []string{"a"}}},
+	}
+
+	for _, tt := range tests {
+		opts, err := parseFlags(testFlags(), tt.arguments)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.arguments, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(opts, tt.expected) {
+			t.Errorf("%q: expected %+v, got %+v", tt.arguments, tt.expected, opts)
+		}
+	}
+}
+
+func TestParseFlagsErrors(t *testing.T) {
+	tests := [][]string{
+		{"--tokens", "script.mk"},
+		{"--tokens", "json"},
+		{"--tokens", "xml", "script.mk"},
+		{"--mode", "run", "script.mk"},
+		{"--unknown"},
+	}
+
+	for _, arguments := range tests {
+		if _, err := parseFlags(testFlags(), arguments); err == nil {
+			t.Errorf("%q: expected an error", arguments)
+		}
+	}
+}
+
+func testFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("monkey", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	return flags
+}
@@ -23,7 +23,50 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return e.outer.Get(name)
 }
 
+// Names returns every name that can be found from this scope, the outer
+// scopes included
+func (e *Environment) Names() []string {
+	var names []string
+	seen := map[string]bool{}
+
+	for scope := e; scope != nil; scope = scope.outer {
+		for name := range scope.store {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+// Set overwrites name in the scope it was defined in, so assignments inside a
+// function change outer variables. New names are defined in the current scope.
 func (e *Environment) Set(name string, obj Object) Object {
+	if !e.Update(name, obj) {
+		e.store[name] = obj
+	}
+	return obj
+}
+
+// SetLocal defines name in the current scope, shadowing any outer variable
+func (e *Environment) SetLocal(name string, obj Object) Object {
 	e.store[name] = obj
 	return obj
 }
+
+// Update overwrites name in the scope it was defined in, it reports false if
+// name isn't defined in any scope
+func (e *Environment) Update(name string, obj Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = obj
+		return true
+	}
+
+	if e.outer == nil {
+		return false
+	}
+
+	return e.outer.Update(name, obj)
+}
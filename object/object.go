@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"../ast"
+	"../tokens"
 )
-import "../ast"
 
 type Type string
 
@@ -18,6 +20,8 @@ const (
 	LIST     Type = "list"
 	RECORD   Type = "record"
 	RETURN   Type = "return_type"
+	BREAK    Type = "break_type"
+	CONTINUE Type = "continue_type"
 	ERROR    Type = "error_type"
 )
 
@@ -64,7 +68,7 @@ func (o String) Type() Type              { return STRING }
 func (o String) Bool() bool              { return true }
 func (o String) Add(other String) String { return o + other }
 func (o String) String() string          { return string(o) }
-func (o String) Json(int) string         { return "\"" + string(o) + "\"" }
+func (o String) Json(int) string         { return jsonQuote(string(o)) }
 func (o String) Equal(object Object) Boolean {
 	if object.Type() != STRING {
 		return false
@@ -209,8 +213,13 @@ func (o Record) Equal(object Object) Boolean {
 		return false
 	}
 
-	for key := range o.Values {
-		if !o.Values[key].Equal(record.Values[key]) {
+	for key, value := range o.Values {
+		other, ok := record.Values[key]
+		if !ok {
+			return false
+		}
+
+		if !value.Equal(other) {
 			return false
 		}
 	}
@@ -251,22 +260,59 @@ func (o Record) Json(indent int) string {
 	in := strings.Repeat("  ", indent)
 
 	for key, val := range o.Values {
-		values = append(values, fmt.Sprintf("%v%q: %v", in, key, val.Json(indent+1)))
+		values = append(values, fmt.Sprintf("%v%v: %v", in, jsonQuote(key), val.Json(indent+1)))
+	}
+
+	return fmt.Sprintf("{\n%v%v}", strings.Join(values, ",\n"), in)
+}
+
+// jsonQuote quotes a string the way JSON wants it. Go's %q is close, but
+// escapes like \x00 and \U0001F600 aren't valid JSON.
+func jsonQuote(str string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+
+	for _, char := range str {
+		switch char {
+		case '"':
+			builder.WriteString(`\"`)
+		case '\\':
+			builder.WriteString(`\\`)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		default:
+			if char < 0x20 {
+				fmt.Fprintf(&builder, `\u%04x`, char)
+			} else {
+				builder.WriteRune(char)
+			}
+		}
 	}
 
-	return fmt.Sprintf("{\n%v%v}", strings.Join(values, "\n"), in)
+	builder.WriteByte('"')
+	return builder.String()
 }
 
 // -------------------------------------------
 // ----------------- ERROR -------------------
 // -------------------------------------------
-type Error string
+type Error struct {
+	Message string
+	Pos     tokens.Pos // set by the evaluator to where the error happened
+}
 
 func (o Error) Type() Type                  { return ERROR }
 func (o Error) Bool() bool                  { return false }
-func (o Error) String() string              { return "ERROR: " + string(o) }
+func (o Error) String() string              { return "ERROR: " + o.Message }
 func (o Error) Equal(object Object) Boolean { return false }
-func (o Error) Json(int) string             { return string(o) }
+func (o Error) Json(int) string             { return o.Message }
+func (o Error) Format(filename string, source string) string {
+	return tokens.FormatError(filename, source, o.Pos, o.Message)
+}
 
 // -------------------------------------------
 // ------------- RETURN VALUE ----------------
@@ -280,3 +326,25 @@ func (o ReturnValue) Bool() bool                  { return o.Value.Bool() }
 func (o ReturnValue) String() string              { return o.Value.String() }
 func (o ReturnValue) Equal(object Object) Boolean { return false }
 func (o ReturnValue) Json(indent int) string      { return o.Value.Json(indent) }
+
+// -------------------------------------------
+// ---------------- BREAK --------------------
+// -------------------------------------------
+type Break struct{}
+
+func (o Break) Type() Type                  { return BREAK }
+func (o Break) Bool() bool                  { return false }
+func (o Break) String() string              { return "break" }
+func (o Break) Equal(object Object) Boolean { return false }
+func (o Break) Json(int) string             { return "null" }
+
+// -------------------------------------------
+// --------------- CONTINUE ------------------
+// -------------------------------------------
+type Continue struct{}
+
+func (o Continue) Type() Type                  { return CONTINUE }
+func (o Continue) Bool() bool                  { return false }
+func (o Continue) String() string              { return "continue" }
+func (o Continue) Equal(object Object) Boolean { return false }
+func (o Continue) Json(int) string             { return "null" }
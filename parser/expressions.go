@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"strings"
+
 	"../ast"
 	"../tokens"
 )
@@ -31,6 +33,16 @@ var precedences = map[tokens.TokenType]int{
 	tokens.MUL:        PRODUCT,
 	tokens.DIV:        PRODUCT,
 	tokens.L_PAREN:    CALL,
+	tokens.DOT:        CALL,
+	tokens.L_BRACKET:  CALL,
+}
+
+// Precedence is how tightly an infix operator binds, LOWEST for other tokens
+func Precedence(token tokens.TokenType) int {
+	if p, ok := precedences[token]; ok {
+		return p
+	}
+	return LOWEST
 }
 
 type prefixParseFunc func() ast.Expression
@@ -42,12 +54,14 @@ func (pars *Parser) installParseFuncs() {
 	pars.prefixParseFuncs[tokens.TRUE] = pars.boolean
 	pars.prefixParseFuncs[tokens.FALSE] = pars.boolean
 	pars.prefixParseFuncs[tokens.STRING] = pars.text
+	pars.prefixParseFuncs[tokens.STRING_START] = pars.interpolation
 	pars.prefixParseFuncs[tokens.NOT] = pars.prefixExpression
 	pars.prefixParseFuncs[tokens.SUB] = pars.prefixExpression
 	pars.prefixParseFuncs[tokens.L_PAREN] = pars.groupedExpression
 	pars.prefixParseFuncs[tokens.FUNC] = pars.functionExpression
 	pars.prefixParseFuncs[tokens.L_BRACKET] = pars.listExpression
-	//pars.prefixParseFuncs[tokens.L_BRACE] = pars.recordExpresion
+	pars.prefixParseFuncs[tokens.L_BRACE] = pars.recordExpression
+	pars.prefixParseFuncs[tokens.ILLEGAL] = pars.illegal
 
 	pars.infixParseFuncs[tokens.ADD] = pars.infixExpression
 	pars.infixParseFuncs[tokens.SUB] = pars.infixExpression
@@ -62,22 +76,22 @@ func (pars *Parser) installParseFuncs() {
 	pars.infixParseFuncs[tokens.AND] = pars.infixExpression
 	pars.infixParseFuncs[tokens.OR] = pars.infixExpression
 	pars.infixParseFuncs[tokens.L_PAREN] = pars.callExpression
-
-	//pars.infixParseFuncs[tokens.DOT] = pars.dotExpression
+	pars.infixParseFuncs[tokens.DOT] = pars.dotExpression
+	pars.infixParseFuncs[tokens.L_BRACKET] = pars.indexExpression
 }
 
 func (pars *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := pars.prefixParseFuncs[pars.currentToken.Type]
 
 	if prefix == nil {
-		pars.addError("unexpected word: %q", pars.currentToken.Type)
+		pars.addError("expected an expression, found %v", describe(pars.currentToken))
 		return nil
 	}
 
 	leftExpression := prefix()
 
 	// do rightside(s)
-	for precedence < pars.peekPrecedence() {
+	for leftExpression != nil && precedence < pars.peekPrecedence() && !pars.peekStartsExpression() {
 		infix := pars.infixParseFuncs[pars.peekToken.Type]
 
 		if infix == nil {
@@ -90,12 +104,24 @@ func (pars *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExpression
 }
 
+// peekStartsExpression reports if the next token is "(", "[" or "-" on a
+// line after the current one. Those can start an expression too, so there
+// they start a new statement instead of calling, indexing or subtracting
+// from the one before.
+func (pars *Parser) peekStartsExpression() bool {
+	switch pars.peekToken.Type {
+	case tokens.L_PAREN, tokens.L_BRACKET, tokens.SUB:
+		// raw strings can span lines, what matters is where they end
+		return pars.peekToken.Pos.Line > pars.currentToken.Pos.Line+strings.Count(pars.currentToken.Raw, "\n")
+	}
+	return false
+}
+
 func (pars *Parser) groupedExpression() ast.Expression {
 	pars.nextToken() // (
 	expression := pars.parseExpression(LOWEST)
 
-	if !pars.nextTokenIf(tokens.R_PAREN) {
-		pars.addError("missing \")\" at the end of expression")
+	if expression == nil || !pars.expectPeek("at the end of expression", tokens.R_PAREN) {
 		return nil
 	}
 
@@ -111,6 +137,11 @@ func (pars *Parser) prefixExpression() ast.Expression {
 	// consume prefix
 	pars.nextToken()
 	expression.RightSide = pars.parseExpression(PREFIX)
+
+	if expression.RightSide == nil {
+		return nil
+	}
+
 	return expression
 }
 
@@ -126,19 +157,31 @@ func (pars *Parser) infixExpression(leftSide ast.Expression) ast.Expression {
 	pars.nextToken()
 	expression.RightSide = pars.parseExpression(precedence)
 
+	if expression.RightSide == nil {
+		return nil
+	}
+
 	return expression
 }
 
-func (pars *Parser) peekPrecedence() int {
-	if p, ok := precedences[pars.peekToken.Type]; ok {
-		return p
+func (pars *Parser) dotExpression(left ast.Expression) ast.Expression {
+	expression := ast.DotExpression{
+		Left:  left,
+		Token: pars.currentToken,
 	}
-	return LOWEST
+
+	if !pars.expectPeek("after \".\"", tokens.IDENT) {
+		return nil
+	}
+
+	expression.Name = pars.currentToken.Literal
+	return expression
+}
+
+func (pars *Parser) peekPrecedence() int {
+	return Precedence(pars.peekToken.Type)
 }
 
 func (pars *Parser) currentPrecedence() int {
-	if p, ok := precedences[pars.currentToken.Type]; ok {
-		return p
-	}
-	return LOWEST
+	return Precedence(pars.currentToken.Type)
 }
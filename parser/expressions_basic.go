@@ -2,6 +2,7 @@ package parser
 
 import (
 	"strconv"
+	"strings"
 
 	"../ast"
 	"../tokens"
@@ -15,10 +16,10 @@ func (pars *Parser) identifier() ast.Expression {
 }
 
 func (pars *Parser) number() ast.Expression {
-	value, err := strconv.ParseFloat(pars.currentToken.Literal, 64)
+	value, err := parseNumber(pars.currentToken.Literal)
 
 	if err != nil {
-		pars.addError("could not parse %q as an number", pars.currentToken.Literal)
+		pars.addError("could not parse %q as a number", pars.currentToken.Literal)
 		return nil
 	}
 
@@ -28,6 +29,19 @@ func (pars *Parser) number() ast.Expression {
 	}
 }
 
+// parseNumber reads a number the way the lexer writes them: decimals like
+// 1_000.5e-3 or whole numbers with a 0x, 0o or 0b prefix
+func parseNumber(literal string) (float64, error) {
+	literal = strings.ReplaceAll(literal, "_", "")
+
+	if len(literal) > 2 && literal[0] == '0' && strings.ContainsRune("xXoObB", rune(literal[1])) {
+		value, err := strconv.ParseUint(literal, 0, 64)
+		return float64(value), err
+	}
+
+	return strconv.ParseFloat(literal, 64)
+}
+
 func (pars *Parser) boolean() ast.Expression {
 	return ast.BooleanExpression{
 		Value: pars.currentToken.Type == tokens.TRUE,
@@ -41,3 +55,36 @@ func (pars *Parser) text() ast.Expression {
 		Token: pars.currentToken,
 	}
 }
+
+// interpolation parses "a {b} c" into its text and expression parts
+func (pars *Parser) interpolation() ast.Expression {
+	expression := ast.InterpolationExpression{Token: pars.currentToken}
+
+	for {
+		if pars.currentToken.Literal != "" {
+			expression.Parts = append(expression.Parts, ast.TextExpression{
+				Value: pars.currentToken.Literal,
+				Token: pars.currentToken,
+			})
+		}
+
+		if pars.currentToken.Type == tokens.STRING_END {
+			return expression
+		}
+
+		pars.nextToken() // "... {
+		part := pars.parseExpression(LOWEST)
+
+		if part == nil || !pars.expectPeek("to end the interpolation", tokens.STRING_MID, tokens.STRING_END) {
+			return nil
+		}
+
+		expression.Parts = append(expression.Parts, part)
+	}
+}
+
+// illegal reports a token the lexer couldn't read, its literal is the reason
+func (pars *Parser) illegal() ast.Expression {
+	pars.addError("%v", pars.currentToken.Literal)
+	return nil
+}
@@ -6,54 +6,59 @@ import (
 )
 
 func (pars *Parser) functionExpression() ast.Expression {
-	expression := ast.FunctionExpression{}
+	expression := ast.FunctionExpression{Token: pars.currentToken}
 
-	if !pars.nextTokenIf(tokens.L_PAREN) {
-		pars.addError("expected \"(\" after function parameters")
+	if !pars.expectPeek("after \"func\"", tokens.L_PAREN) {
 		return nil
 	}
 
 	expression.Parameters = pars.functionParameters()
-	pars.nextToken() // )
-	expression.Body = pars.statements()
-
-	if pars.currentToken.Type != tokens.END {
-		pars.addError("expected \"end\" at the end of function")
-	}
+	pars.nextToken() // ) -> stmts
+	expression.Body = pars.endBlock(expression.Token, "function")
 
 	return expression
 }
 
+// functionParameters parses the parameter names and stops at ")". Broken
+// parameters are skipped so the body can still be parsed.
 func (pars *Parser) functionParameters() []string {
 	var parameters []string
 
-	if pars.peekToken.Type == tokens.R_PAREN {
-		pars.nextToken()
+	if pars.nextTokenIf(tokens.R_PAREN) {
 		return parameters
 	}
 
-	pars.nextToken()
-
-	parameters = append(parameters, pars.currentToken.Literal)
+	for {
+		if !pars.expectPeek("as function parameter", tokens.IDENT) {
+			pars.skipPast(tokens.R_PAREN)
+			return parameters
+		}
 
-	for pars.peekToken.Type == tokens.COMMA {
-		pars.nextToken() // ,
-		pars.nextToken() // parameter
 		parameters = append(parameters, pars.currentToken.Literal)
-	}
 
-	if !pars.nextTokenIf(tokens.R_PAREN) {
-		pars.addError("expected \")\" after function parameters")
-		return nil
-	}
+		if !pars.expectPeek("after function parameter", tokens.COMMA, tokens.R_PAREN) {
+			pars.skipPast(tokens.R_PAREN)
+			return parameters
+		}
 
-	return parameters
+		if pars.currentToken.Type == tokens.R_PAREN {
+			return parameters
+		}
+	}
 }
 
 func (pars *Parser) callExpression(function ast.Expression) ast.Expression {
-	return ast.CallExpression{
-		Function:  function,
-		Token:     pars.currentToken,
-		Arguments: pars.commaList(tokens.R_PAREN),
+	expression := ast.CallExpression{
+		Function: function,
+		Token:    pars.currentToken,
+	}
+	arguments, ok := pars.commaList(tokens.R_PAREN)
+
+	if !ok {
+		return nil
 	}
+
+	expression.Arguments = arguments
+	expression.End = pars.currentToken
+	return expression
 }
@@ -6,34 +6,57 @@ import (
 )
 
 func (pars *Parser) listExpression() ast.Expression {
-	expression := ast.ListExpression{
-		Token:  pars.currentToken,
-		Values: pars.commaList(tokens.R_BRACKET),
+	expression := ast.ListExpression{Token: pars.currentToken}
+	values, ok := pars.commaList(tokens.R_BRACKET)
+
+	if !ok {
+		return nil
 	}
 
+	expression.Values = values
+	expression.End = pars.currentToken
 	return expression
 }
 
-func (pars *Parser) commaList(endToken tokens.TokenType) []ast.Expression {
-	var args []ast.Expression
-
-	if pars.nextTokenIf(endToken) {
-		return args
+func (pars *Parser) indexExpression(left ast.Expression) ast.Expression {
+	expression := ast.IndexExpression{
+		Left:  left,
+		Token: pars.currentToken,
 	}
 
-	pars.nextToken()
-	args = append(args, pars.parseExpression(LOWEST))
+	pars.nextToken() // [ -> index
+	expression.Index = pars.parseExpression(LOWEST)
 
-	for pars.peekToken.Type == tokens.COMMA {
-		pars.nextToken()
-		pars.nextToken()
-		args = append(args, pars.parseExpression(LOWEST))
-	}
-
-	if !pars.nextTokenIf(endToken) {
-		pars.addError("expected %q at the end of list", endToken)
+	if expression.Index == nil || !pars.expectPeek("after index", tokens.R_BRACKET) {
 		return nil
 	}
 
-	return args
+	return expression
+}
+
+// commaList parses comma separated expressions up to endToken, ok is false if
+// the list is broken. A trailing comma is allowed.
+func (pars *Parser) commaList(endToken tokens.TokenType) (args []ast.Expression, ok bool) {
+	for {
+		if pars.nextTokenIf(endToken) {
+			return args, true
+		}
+
+		pars.nextToken() // ( or , -> expression
+		arg := pars.parseExpression(LOWEST)
+
+		if arg == nil {
+			return nil, false
+		}
+
+		args = append(args, arg)
+
+		if !pars.expectPeek("in list", tokens.COMMA, endToken) {
+			return nil, false
+		}
+
+		if pars.currentToken.Type == endToken {
+			return args, true
+		}
+	}
 }
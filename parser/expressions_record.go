@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"../ast"
+	"../tokens"
+)
+
+func (pars *Parser) recordExpression() ast.Expression {
+	expression := ast.RecordExpression{
+		Keys:   []string{},
+		Values: []ast.Expression{},
+		Token:  pars.currentToken,
+	}
+
+	seen := map[string]bool{}
+
+	for pars.peekToken.Type != tokens.R_BRACE {
+		if !pars.expectPeek("as record key", tokens.IDENT, tokens.STRING) {
+			return nil
+		}
+
+		key := pars.currentToken.Literal
+
+		// not worth giving up on the record for, so just report it
+		if seen[key] {
+			pars.addError("duplicate key %q in record", key)
+		}
+		seen[key] = true
+
+		if !pars.expectPeek("after record key", tokens.ASSIGN) {
+			return nil
+		}
+
+		pars.nextToken() // = -> value
+		value := pars.parseExpression(LOWEST)
+
+		if value == nil {
+			return nil
+		}
+
+		expression.Keys = append(expression.Keys, key)
+		expression.Values = append(expression.Values, value)
+
+		// the comma after the last value is optional
+		if !pars.nextTokenIf(tokens.COMMA) {
+			break
+		}
+	}
+
+	if !pars.expectPeek("at the end of record", tokens.R_BRACE) {
+		return nil
+	}
+
+	expression.End = pars.currentToken
+	return expression
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"../ast"
 	"../lexer"
@@ -17,13 +18,29 @@ type Parser struct {
 	prefixParseFuncs map[tokens.TokenType]prefixParseFunc
 	infixParseFuncs  map[tokens.TokenType]infixParseFunc
 
-	errors []string
+	errors []Error
+	trivia []tokens.Token
+}
+
+type Error struct {
+	Pos      tokens.Pos
+	Expected []tokens.TokenType // empty if no particular token was expected
+	Found    tokens.Token
+	Message  string
+}
+
+func (err Error) Error() string {
+	return fmt.Sprintf("%v: %v", err.Pos, err.Message)
+}
+
+func (err Error) Format(filename string, source string) string {
+	return tokens.FormatError(filename, source, err.Pos, err.Message)
 }
 
 func New(lex *lexer.Lexer) *Parser {
 	pars := Parser{
 		lex:              lex,
-		errors:           []string{},
+		errors:           []Error{},
 		prefixParseFuncs: map[tokens.TokenType]prefixParseFunc{},
 		infixParseFuncs:  map[tokens.TokenType]infixParseFunc{},
 	}
@@ -35,25 +52,80 @@ func New(lex *lexer.Lexer) *Parser {
 	return &pars
 }
 
+// ParseProgram parses the whole input. A syntax error doesn't stop the parser,
+// it skips to the next statement so every error is found in one pass. The
+// program is only complete if there are no errors.
 func (pars *Parser) ParseProgram() ast.Program {
 	program := ast.Program{}
+	program.Body.Token = pars.currentToken
 
 	for pars.currentToken.Type != tokens.EOF {
-		statement := pars.parseStatement()
-
-		if pars.HasErrors() {
-			break
+		if statement := pars.statement(); statement != nil {
+			program.Body.Statements = append(program.Body.Statements, statement)
 		}
+	}
 
-		if statement == nil {
-			pars.addError("nil statement found, forgot to add error :(")
-		}
+	program.Body.End = pars.currentToken
+	program.Trivia = pars.trivia
+	return program
+}
+
+// statement parses a statement and moves to the token after it. A broken
+// statement returns nil, and the parser is moved to where the next statement
+// most likely starts.
+func (pars *Parser) statement() ast.Statement {
+	start := pars.currentToken
+	statement := pars.parseStatement()
 
-		program.Body.Statements = append(program.Body.Statements, statement)
+	if statement != nil {
 		pars.nextToken()
+		return statement
 	}
 
-	return program
+	line := pars.currentToken.Pos.Line
+
+	// the token a broken statement starts with is always skipped, it might
+	// not be able to start a statement at all
+	if pars.currentToken.Pos == start.Pos {
+		pars.nextToken()
+	}
+
+	pars.synchronize(line)
+	return nil
+}
+
+// synchronize skips the rest of a broken statement on the given line. It
+// stops at statement and block keywords or at the first token on a new line.
+func (pars *Parser) synchronize(line int) {
+	for !pars.atStatementBoundary() && pars.currentToken.Pos.Line == line {
+		pars.nextToken()
+	}
+}
+
+func (pars *Parser) atStatementBoundary() bool {
+	switch pars.currentToken.Type {
+	case tokens.EOF, tokens.END, tokens.ELSE, tokens.ELSEIF,
+		tokens.IF, tokens.LOOP, tokens.RETURN, tokens.BREAK, tokens.CONTINUE, tokens.LOCAL:
+		return true
+	}
+	return false
+}
+
+// skipPast moves past the next token of the given type if it's on the same
+// line, skipping whatever comes before it. It's used to get back on track
+// after an error, as in "if a b then".
+func (pars *Parser) skipPast(token tokens.TokenType) {
+	line := pars.currentToken.Pos.Line
+
+	for pars.peekToken.Type != token && pars.peekToken.Pos.Line == line {
+		switch pars.peekToken.Type {
+		case tokens.EOF, tokens.END, tokens.ELSE, tokens.ELSEIF:
+			return
+		}
+		pars.nextToken()
+	}
+
+	pars.nextTokenIf(token)
 }
 
 func (pars *Parser) nextTokenIf(token tokens.TokenType) bool {
@@ -65,13 +137,98 @@ func (pars *Parser) nextTokenIf(token tokens.TokenType) bool {
 	return true
 }
 
+// expectPeek moves to the next token if it's one of the expected ones,
+// otherwise it reports what was found instead. context describes where the
+// token was expected, like "after if condition".
+func (pars *Parser) expectPeek(context string, expected ...tokens.TokenType) bool {
+	for _, token := range expected {
+		if pars.nextTokenIf(token) {
+			return true
+		}
+	}
+
+	// the lexer already knows what's wrong with its own tokens
+	if pars.peekToken.Type == tokens.ILLEGAL {
+		pars.addErrorAt(pars.peekToken, "%v", pars.peekToken.Literal)
+		return false
+	}
+
+	names := []string{}
+
+	for _, token := range expected {
+		name := fmt.Sprintf("%q", token)
+
+		switch token {
+		case tokens.IDENT:
+			name = "a name"
+		case tokens.STRING:
+			name = "a string"
+		case tokens.STRING_MID, tokens.STRING_END:
+			name = `"}"`
+		}
+
+		if len(names) == 0 || names[len(names)-1] != name {
+			names = append(names, name)
+		}
+	}
+
+	pars.errors = append(pars.errors, Error{
+		Pos:      pars.peekToken.Pos,
+		Expected: expected,
+		Found:    pars.peekToken,
+		Message: fmt.Sprintf("expected %v %v, found %v",
+			strings.Join(names, " or "), context, describe(pars.peekToken)),
+	})
+
+	return false
+}
+
 func (pars *Parser) nextToken() {
 	pars.currentToken = pars.peekToken
 	pars.peekToken = pars.lex.NextToken()
+
+	if trivia := pars.peekToken.Trivia; trivia != nil {
+		pars.trivia = append(pars.trivia, trivia.Leading...)
+
+		if trivia.Trailing != nil {
+			pars.trivia = append(pars.trivia, *trivia.Trailing)
+		}
+	}
 }
 
+// addError reports an error at the current token
 func (pars *Parser) addError(err string, args ...interface{}) {
-	pars.errors = append(pars.errors, fmt.Sprintf(err, args...))
+	pars.addErrorAt(pars.currentToken, err, args...)
+}
+
+func (pars *Parser) addErrorAt(token tokens.Token, err string, args ...interface{}) {
+	pars.errors = append(pars.errors, Error{
+		Pos:     token.Pos,
+		Found:   token,
+		Message: fmt.Sprintf(err, args...),
+	})
+}
+
+// describe names a token the way it's written in error messages
+func describe(token tokens.Token) string {
+	switch token.Type {
+	case tokens.EOF:
+		return "end of file"
+	case tokens.IDENT:
+		return fmt.Sprintf("name %q", token.Literal)
+	case tokens.NUMBER:
+		return "number " + token.Literal
+	case tokens.STRING:
+		return fmt.Sprintf("string %q", token.Literal)
+	case tokens.STRING_START:
+		return fmt.Sprintf("string %q", token.Literal+"{")
+	case tokens.STRING_MID, tokens.STRING_END:
+		return `"}"`
+	case tokens.ILLEGAL:
+		return token.Literal
+	default:
+		return fmt.Sprintf("%q", token.Type)
+	}
 }
 
 func (pars *Parser) PrintErrors() {
@@ -83,6 +240,10 @@ func (pars *Parser) PrintErrors() {
 	}
 }
 
+func (pars *Parser) Errors() []Error {
+	return pars.errors
+}
+
 func (pars *Parser) HasErrors() bool {
 	return len(pars.errors) > 0
 }
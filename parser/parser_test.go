@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"../lexer"
+	"../tokens"
 )
 
 func TestAssignmentAndBasicTypes(t *testing.T) {
@@ -21,12 +22,34 @@ func TestAssignmentAndBasicTypes(t *testing.T) {
 	})
 }
 
+func TestNumbers(t *testing.T) {
+	testParser(t, `
+		a = 0x1F
+		b = 0b1010 + 0o755
+		c = 1_000_000
+		d = .5
+		e = 1.5e3
+		f = 1e-9
+	`, []string{
+		"a = 31",
+		"b = (10 + 493)",
+		"c = 1000000",
+		"d = 0.5",
+		"e = 1500",
+		"f = 0.000000001",
+	})
+}
+
 func TestListType(t *testing.T) {
 	testParser(t, `
 		a = []
 		b = [1]
 		c = [1,2,3]
 		d = [123.4, "hellö", true]
+		e = [
+			1,
+			2,
+		]
 	`, []string{
 		`a = []`,
 		`b = [
@@ -42,7 +65,90 @@ func TestListType(t *testing.T) {
 	"hellö",
 	true
 ]`,
+		`e = [
+	1,
+	2
+]`,
+	})
+}
+
+func TestLocalStatement(t *testing.T) {
+	testParser(t, `
+		local a = 1
+		f = func ()
+			local b = a + 1
+		end
+	`, []string{
+		"local a = 1",
+		`f = func ()
+	local b = (a + 1)
+end`,
+	})
+}
+
+func TestRecordType(t *testing.T) {
+	testParser(t, `
+		a = {}
+		b = {x = 1}
+		c = {x = 1, "y z" = "two", end_ = true, Größe2 = 3, "1a" = 4, "End" = 5,}
+		d = {
+			inner = {list = [1]},
+		}
+	`, []string{
+		`a = {}`,
+		`b = {
+	x = 1
+}`,
+		`c = {
+	x = 1,
+	"y z" = "two",
+	end_ = true,
+	Größe2 = 3,
+	"1a" = 4,
+	End = 5
+}`,
+		`d = {
+	inner = {
+		list = [
+			1
+		]
+	}
+}`,
+	})
+}
+
+func TestInterpolation(t *testing.T) {
+	testParser(t, `
+		a = "hi {name}!"
+		b = "{1 + 2}{xs[0]}"
+		c = "{ "inner {x}" } \{not} {{k = 1}.k}"
+	`, []string{
+		`a = "hi {name}!"`,
+		`b = "{(1 + 2)}{xs[0]}"`,
+		`c = "{"inner {x}"} \{not} {{
+	k = 1
+}.k}"`,
 	})
+
+	pars := New(lexer.New("a = \"x {}\"\nb = \"{b c}\"\nc = \"{c\"\nd = \"}\""))
+	pars.ParseProgram()
+
+	expected := []string{
+		`1:9: expected an expression, found "}"`,
+		`2:9: expected "}" to end the interpolation, found name "c"`,
+		`3:8: unterminated string`,
+	}
+
+	if len(pars.Errors()) != len(expected) {
+		pars.PrintErrors()
+		t.Fatalf("expected %v errors, got %v", len(expected), len(pars.Errors()))
+	}
+
+	for i, err := range pars.Errors() {
+		if err.Error() != expected[i] {
+			t.Fatalf("expected error %v to be:\n%v\ninstead we got:\n%v", i, expected[i], err.Error())
+		}
+	}
 }
 
 func TestPrefixExpression(t *testing.T) {
@@ -107,6 +213,68 @@ func TestInfixExpressions(t *testing.T) {
 	})
 }
 
+func TestDotExpression(t *testing.T) {
+	testParser(t, `
+		a = b.c
+		d = fs.read("x.txt")
+		e = a.b.c(1).d
+		f = -list.has(xs, 1) + 1
+	`, []string{
+		"a = b.c",
+		`d = fs.read("x.txt")`,
+		"e = a.b.c(1).d",
+		"f = (-list.has(xs, 1) + 1)",
+	})
+}
+
+func TestIndexExpression(t *testing.T) {
+	testParser(t, `
+		a = xs[0]
+		b = xs[-1 + 2][1]
+		c = rec["key"].list[i]
+		xs[0] = 1
+		rec.key += 1
+		rec["a"].b[2] *= 3
+	`, []string{
+		"a = xs[0]",
+		"b = xs[(-1 + 2)][1]",
+		`c = rec["key"].list[i]`,
+		"xs[0] = 1",
+		"rec.key += 1",
+		`rec["a"].b[2] *= 3`,
+	})
+}
+
+func TestExpressionStatement(t *testing.T) {
+	testParser(t, `
+		print("hello")
+		fs.mkdir(path.join(a, "b"))
+		list.each(xs, func (x)
+			print(x)
+		end)
+		xs[0]
+		1 + 2
+	`, []string{
+		`print("hello")`,
+		`fs.mkdir(path.join(a, "b"))`,
+		`list.each(xs, func (x)
+	print(x)
+end)`,
+		"xs[0]",
+		"(1 + 2)",
+	})
+}
+
+func TestNewlineEndsExpression(t *testing.T) {
+	testParser(t, "x = 1\n-1", []string{"x = 1", "-1"})
+	testParser(t, "print(x)\n(f)()", []string{"print(x)", "f()"})
+	testParser(t, "a = b\n[1, 2]", []string{"a = b", "[\n\t1,\n\t2\n]"})
+	testParser(t, "s = `one\ntwo`\n[0]", []string{`s = "one\ntwo"`, "[\n\t0\n]"})
+
+	// the rest of the operators carry on, there is nothing else they can mean
+	testParser(t, "x = 1 -\n1\ny = f(\n1)[\n0] +\n2", []string{"x = (1 - 1)", "y = (f(1)[0] + 2)"})
+}
+
 func TestIfStatement(t *testing.T) {
 	testParser(t, `
 		if true then end
@@ -173,6 +341,13 @@ func TestLoopStatement(t *testing.T) {
 				a = 2
 			end
 		end
+
+		loop
+			if a then
+				break
+			end
+			continue
+		end
 	`, []string{
 		"loop\nend",
 		`loop
@@ -180,6 +355,12 @@ func TestLoopStatement(t *testing.T) {
 	if true then
 		a = 2
 	end
+end`,
+		`loop
+	if a then
+		break
+	end
+	continue
 end`,
 	})
 }
@@ -215,10 +396,9 @@ func TestReturnStatement(t *testing.T) {
 	asdf = 123
 	return
 end`,
-		`func (a, b, c)
+		`a = func (a, b, c)
 	return
-end
-`,
+end`,
 		`if asdf then
 	return
 if false then
@@ -229,12 +409,103 @@ end`,
 	})
 }
 
+func TestErrorPositions(t *testing.T) {
+	input := "a = 1\nif a b = 2 end"
+	pars := New(lexer.New(input))
+	pars.ParseProgram()
+
+	if !pars.HasErrors() {
+		t.Fatalf("expected a parser error")
+	}
+
+	expected := "script.mk:2:6: expected \"then\" after if condition, found name \"b\"\nif a b = 2 end\n     ^"
+
+	if pars.Errors()[0].Format("script.mk", input) != expected {
+		t.Fatalf("expected error:\n%v\ninstead we got:\n%v", expected, pars.Errors()[0].Format("script.mk", input))
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	pars := New(lexer.New(`a = (1 + 2
+b = 3
+if a b = 2 end
+print(1 2)
+c = func (x, 1) return x end
+d = 4 +
+loop
+	e = )
+	f = {x = 1, x = 2}
+end
+g = [1, 2
+h = func ()
+	local = 1
+`))
+	program := pars.ParseProgram()
+
+	expected := []string{
+		`2:1: expected ")" at the end of expression, found name "b"`,
+		`3:6: expected "then" after if condition, found name "b"`,
+		`4:9: expected "," or ")" in list, found number 2`,
+		`5:14: expected a name as function parameter, found number 1`,
+		`7:1: expected an expression, found "loop"`,
+		`8:6: expected an expression, found ")"`,
+		`9:14: duplicate key "x" in record`,
+		`12:1: expected "," or "]" in list, found name "h"`,
+		`13:8: expected a name after "local", found "="`,
+		`14:1: expected "end" to close the function on line 12, found end of file`,
+	}
+
+	if len(pars.Errors()) != len(expected) {
+		pars.PrintErrors()
+		t.Fatalf("expected %v errors, got %v", len(expected), len(pars.Errors()))
+	}
+
+	for i, err := range pars.Errors() {
+		if err.Error() != expected[i] {
+			t.Fatalf("expected error %v to be:\n%v\ninstead we got:\n%v", i, expected[i], err.Error())
+		}
+	}
+
+	// the statements that could be parsed are still there
+	if len(program.Body.Statements) != 5 {
+		t.Fatalf("expected 5 statements, got %v", len(program.Body.Statements))
+	}
+
+	found := pars.Errors()[0]
+
+	if len(found.Expected) != 1 || found.Expected[0] != tokens.R_PAREN || found.Found.Literal != "b" {
+		t.Fatalf("expected error to record the expected and found tokens, got %+v", found)
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	lex := lexer.New("# start\na = 1 # one\n\nif a then\n\t# inside\nend\n# done")
+	lex.KeepTrivia()
+	program := New(lex).ParseProgram()
+
+	expected := []string{"# start", "# one", "", "# inside", "# done"}
+
+	if len(program.Trivia) != len(expected) {
+		t.Fatalf("expected %v trivia tokens, got %+v", len(expected), program.Trivia)
+	}
+
+	for i, trivia := range program.Trivia {
+		if trivia.Literal != expected[i] {
+			t.Fatalf("expected trivia %v to be %q, got %q", i, expected[i], trivia.Literal)
+		}
+	}
+
+	if program.Trivia[2].Type != tokens.BLANK_LINE || program.Trivia[2].Pos.Line != 3 {
+		t.Fatalf("expected a blank line on line 3, got %+v", program.Trivia[2])
+	}
+}
+
 func testParser(t *testing.T, input string, expected []string) {
 	pars := New(lexer.New(input))
 	program := pars.ParseProgram()
 
 	if pars.HasErrors() {
-		t.Fatalf("parser found an error:" + pars.errors[0])
+		t.Fatalf("parser found an error: %v", pars.errors[0])
 	}
 
 	if len(program.Body.Statements) != len(expected) {
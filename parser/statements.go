@@ -7,76 +7,126 @@ import (
 
 func (pars *Parser) parseStatement() ast.Statement {
 	switch pars.currentToken.Type {
-	case tokens.IDENT:
-		switch pars.peekToken.Type {
-		case tokens.ASSIGN:
-			return pars.assignmentStatement()
-		case tokens.ADD_ASSIGN, tokens.SUB_ASSIGN, tokens.MUL_ASSIGN, tokens.DIV_ASSIGN:
-			return pars.shorthandAssignmentStatements()
-		}
 	case tokens.RETURN:
 		return pars.returnStatement()
 	case tokens.IF:
 		return pars.ifStatement()
 	case tokens.LOOP:
 		return pars.loopStatement()
+	case tokens.LOCAL:
+		return pars.localStatement()
+	case tokens.BREAK:
+		return ast.BreakStatement{Token: pars.currentToken}
+	case tokens.CONTINUE:
+		return ast.ContinueStatement{Token: pars.currentToken}
 	}
 
-	return nil
-	//return pars.expressionStatement()
+	return pars.expressionStatement()
 }
 
-//func (pars *Parser) expressionStatement() ast.ExpressionStatement {
-//	token := pars.currentToken
-//	expr := pars.parseExpression(LOWEST)
-//
-//	return ast.ExpressionStatement{
-//		Expression: expr,
-//		Token:      token,
-//	}
-//}
-
-func (pars *Parser) assignmentStatement() ast.AssignmentStatement {
-	stmt := ast.AssignmentStatement{
-		Name: pars.currentToken.Literal,
+func (pars *Parser) expressionStatement() ast.Statement {
+	token := pars.currentToken
+	expression := pars.parseExpression(LOWEST)
+
+	switch pars.peekToken.Type {
+	case tokens.ASSIGN, tokens.ADD_ASSIGN, tokens.SUB_ASSIGN, tokens.MUL_ASSIGN, tokens.DIV_ASSIGN:
+		return pars.assignmentStatement(token, expression)
 	}
 
-	pars.nextToken() // =
-	pars.nextToken() // start expression
-	stmt.Value = pars.parseExpression(LOWEST)
-	return stmt
+	if expression == nil {
+		return nil
+	}
+
+	return ast.ExpressionStatement{
+		Expression: expression,
+		Token:      token,
+	}
 }
 
-func (pars *Parser) shorthandAssignmentStatements() ast.ShorthandAssignmentStatement {
-	stmt := ast.ShorthandAssignmentStatement{
-		Name: pars.currentToken.Literal,
+func (pars *Parser) assignmentStatement(token tokens.Token, target ast.Expression) ast.Statement {
+	switch target.(type) {
+	case ast.IdentifierExpression, ast.DotExpression, ast.IndexExpression:
+	default:
+		if target != nil {
+			pars.addErrorAt(token, "cannot assign to %v", target.String(0))
+		}
+		return nil
 	}
 
-	pars.nextToken() // +=
-	stmt.Operator = pars.currentToken.Type
+	pars.nextToken() // = or +=
+	operator := pars.currentToken.Type
 	pars.nextToken() // start expression
+	value := pars.parseExpression(LOWEST)
+
+	if value == nil {
+		return nil
+	}
+
+	if operator == tokens.ASSIGN {
+		return ast.AssignmentStatement{
+			Target: target,
+			Value:  value,
+			Token:  token,
+		}
+	}
+
+	return ast.ShorthandAssignmentStatement{
+		Target:   target,
+		Value:    value,
+		Operator: operator,
+		Token:    token,
+	}
+}
+
+func (pars *Parser) localStatement() ast.Statement {
+	token := pars.currentToken
+
+	if !pars.expectPeek("after \"local\"", tokens.IDENT) {
+		return nil
+	}
+
+	stmt := ast.AssignmentStatement{
+		Target: pars.identifier(),
+		Local:  true,
+		Token:  token,
+	}
+
+	if !pars.expectPeek("after local "+stmt.Target.String(0), tokens.ASSIGN) {
+		return nil
+	}
+
+	pars.nextToken() // = -> expression
 	stmt.Value = pars.parseExpression(LOWEST)
+
+	if stmt.Value == nil {
+		return nil
+	}
+
 	return stmt
 }
 
-func (pars *Parser) returnStatement() ast.ReturnStatement {
-	pars.nextToken()
+func (pars *Parser) returnStatement() ast.Statement {
+	stmt := ast.ReturnStatement{Token: pars.currentToken}
 
-	if pars.currentToken.Type == tokens.END ||
-		pars.currentToken.Type == tokens.ELSEIF ||
-		pars.currentToken.Type == tokens.ELSE {
-
-		return ast.ReturnStatement{}
+	// a bare return is followed directly by the end of its block
+	switch pars.peekToken.Type {
+	case tokens.END, tokens.ELSEIF, tokens.ELSE, tokens.EOF:
+		return stmt
 	}
 
-	return ast.ReturnStatement{
-		Value: pars.parseExpression(LOWEST),
+	pars.nextToken()
+	stmt.Value = pars.parseExpression(LOWEST)
+
+	if stmt.Value == nil {
+		return nil
 	}
+
+	return stmt
 }
 
 func (pars *Parser) loopStatement() ast.LoopStatement {
-	statement := ast.LoopStatement{}
+	statement := ast.LoopStatement{Token: pars.currentToken}
 	pars.nextToken() // loop -> stmts
-	statement.Body = pars.statements()
+	statement.Body = pars.endBlock(statement.Token, "loop")
 	return statement
 }
@@ -1,80 +1,94 @@
 package parser
 
 import (
+	"fmt"
+
 	"../ast"
 	"../tokens"
 )
 
 func (pars *Parser) ifStatement() ast.Statement {
-	stmt := ast.IfStatement{}
+	stmt := ast.IfStatement{Token: pars.currentToken}
 
+	keyword := pars.currentToken.Type
 	pars.nextToken() // if -> expression
 
-	stmt.Conditions = append(stmt.Conditions, pars.parseExpression(LOWEST))
-
-	if !pars.nextTokenIf(tokens.THEN) {
-		pars.addError("expected \"then\" after if condition")
-		return nil
-	}
-
-	pars.nextToken() // then -> stmts
+	for {
+		stmt.Conditions = append(stmt.Conditions, pars.parseExpression(LOWEST))
 
-	stmt.Consequences = append(stmt.Consequences, pars.ifBlockStatements())
+		// keep parsing the block after a missing "then", the block is
+		// usually fine and skipping it only leads to more errors
+		if !pars.expectPeek(fmt.Sprintf("after %v condition", keyword), tokens.THEN) {
+			pars.skipPast(tokens.THEN)
+		}
 
-	for pars.currentToken.Type == tokens.ELSEIF {
-		pars.nextToken()
-		stmt.Conditions = append(stmt.Conditions, pars.parseExpression(LOWEST))
+		pars.nextToken() // then -> stmts
+		stmt.Consequences = append(stmt.Consequences, pars.block(tokens.ELSEIF, tokens.ELSE, tokens.END))
 
-		if !pars.nextTokenIf(tokens.THEN) {
-			pars.addError("expected \"then\" after elseif condition")
-			return nil
+		if pars.currentToken.Type != tokens.ELSEIF {
+			break
 		}
 
-		pars.nextToken() // then
-		stmt.Consequences = append(stmt.Consequences, pars.ifBlockStatements())
+		keyword = pars.currentToken.Type
+		pars.nextToken() // elseif -> expression
 	}
 
 	if pars.currentToken.Type == tokens.ELSE {
+		stmt.Conditions = append(stmt.Conditions, ast.BooleanExpression{Value: true, Token: pars.currentToken})
 		pars.nextToken() // consume else
-		stmt.Conditions = append(stmt.Conditions, ast.BooleanExpression{Value: true})
-		stmt.Consequences = append(stmt.Consequences, pars.statements())
+		stmt.Consequences = append(stmt.Consequences, pars.endBlock(stmt.Token, "if statement"))
+	} else if pars.currentToken.Type != tokens.END {
+		pars.missingEnd(stmt.Token, "if statement")
 	}
 
 	return stmt
 }
 
-func (pars *Parser) statements() ast.BlockStatement {
+// block parses statements until one of the end tokens, which becomes the
+// current token, or the end of the file
+func (pars *Parser) block(end ...tokens.TokenType) ast.BlockStatement {
 	stmts := ast.BlockStatement{
 		Statements: []ast.Statement{},
 		Token:      pars.currentToken,
 	}
 
-	for pars.currentToken.Type != tokens.END && pars.currentToken.Type != tokens.EOF {
-		stmts.Statements = append(stmts.Statements, pars.parseStatement())
-		pars.nextToken()
+	for pars.currentToken.Type != tokens.EOF && !pars.currentIs(end...) {
+		if statement := pars.statement(); statement != nil {
+			stmts.Statements = append(stmts.Statements, statement)
+		}
 	}
 
+	stmts.End = pars.currentToken
+	return stmts
+}
+
+// endBlock parses the statements of a block closed by "end", start is the
+// token that opened the block
+func (pars *Parser) endBlock(start tokens.Token, name string) ast.BlockStatement {
+	stmts := pars.block(tokens.END)
+
 	if pars.currentToken.Type != tokens.END {
-		pars.addError("expected \"end\" at the end of ...")
-		return stmts
+		pars.missingEnd(start, name)
 	}
 
 	return stmts
 }
 
-func (pars *Parser) ifBlockStatements() ast.BlockStatement {
-	stmts := ast.BlockStatement{
-		Statements: []ast.Statement{},
-		Token:      pars.currentToken,
-	}
+func (pars *Parser) missingEnd(start tokens.Token, name string) {
+	pars.errors = append(pars.errors, Error{
+		Pos:      pars.currentToken.Pos,
+		Expected: []tokens.TokenType{tokens.END},
+		Found:    pars.currentToken,
+		Message: fmt.Sprintf("expected \"end\" to close the %v on line %v, found %v",
+			name, start.Pos.Line, describe(pars.currentToken)),
+	})
+}
 
-	for pars.currentToken.Type != tokens.ELSEIF &&
-		pars.currentToken.Type != tokens.ELSE &&
-		pars.currentToken.Type != tokens.END &&
-		pars.currentToken.Type != tokens.EOF {
-		stmts.Statements = append(stmts.Statements, pars.parseStatement())
-		pars.nextToken()
+func (pars *Parser) currentIs(types ...tokens.TokenType) bool {
+	for _, t := range types {
+		if pars.currentToken.Type == t {
+			return true
+		}
 	}
-
-	return stmts
+	return false
 }
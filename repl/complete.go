@@ -0,0 +1,77 @@
+package repl
+
+import (
+	"sort"
+	"strings"
+
+	"../evaluator"
+	"../object"
+	"../tokens"
+)
+
+// complete returns where the word before the cursor starts and the words it
+// can be completed to. Keywords and every name in env are completed, and
+// after a dot the fields of the record before it, so "fs.re" gives
+// "fs.read".
+func complete(line []rune, cursor int, env *object.Environment) (int, []string) {
+	start := cursor
+
+	for start > 0 && (tokens.IsIdentifierPart(line[start-1]) || line[start-1] == '.') {
+		start--
+	}
+
+	word := string(line[start:cursor])
+
+	if word == "" {
+		return start, nil
+	}
+
+	dot := strings.LastIndexByte(word, '.')
+
+	if dot == -1 {
+		names := append(tokens.Keywords(), evaluator.Names(env)...)
+		return start, withPrefix(names, word)
+	}
+
+	path := strings.Split(word[:dot], ".")
+	value, ok := evaluator.Lookup(path[0], env)
+
+	for _, name := range path[1:] {
+		if !ok {
+			break
+		}
+
+		var record object.Record
+		if record, ok = value.(object.Record); ok {
+			value, ok = record.Values[name]
+		}
+	}
+
+	record, isRecord := value.(object.Record)
+
+	if !ok || !isRecord {
+		return start, nil
+	}
+
+	var fields []string
+
+	for name := range record.Values {
+		fields = append(fields, word[:dot+1]+name)
+	}
+
+	return start, withPrefix(fields, word)
+}
+
+// withPrefix returns the words that start with prefix, sorted
+func withPrefix(words []string, prefix string) []string {
+	var matches []string
+
+	for _, word := range words {
+		if strings.HasPrefix(word, prefix) {
+			matches = append(matches, word)
+		}
+	}
+
+	sort.Strings(matches)
+	return matches
+}
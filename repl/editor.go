@@ -0,0 +1,518 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// keys as they are read from the terminal, escape sequences are read whole
+const (
+	keyCtrlA     = "\x01"
+	keyCtrlB     = "\x02"
+	keyCtrlC     = "\x03"
+	keyCtrlD     = "\x04"
+	keyCtrlE     = "\x05"
+	keyCtrlF     = "\x06"
+	keyCtrlG     = "\x07"
+	keyCtrlH     = "\x08"
+	keyTab       = "\t"
+	keyNewline   = "\n"
+	keyCtrlK     = "\x0b"
+	keyEnter     = "\r"
+	keyCtrlN     = "\x0e"
+	keyCtrlP     = "\x10"
+	keyCtrlR     = "\x12"
+	keyCtrlU     = "\x15"
+	keyCtrlW     = "\x17"
+	keyBackspace = "\x7f"
+
+	keyUp     = "\x1b[A"
+	keyDown   = "\x1b[B"
+	keyRight  = "\x1b[C"
+	keyLeft   = "\x1b[D"
+	keyHome   = "\x1b[H"
+	keyEnd    = "\x1b[F"
+	keyHomeVt = "\x1b[1~"
+	keyEndVt  = "\x1b[4~"
+	keyDelete = "\x1b[3~"
+)
+
+// historyLimit is how many lines of history are kept between sessions
+const historyLimit = 1000
+
+var errInterrupted = errors.New("interrupted")
+
+// lineReader reads the input of the repl a line at a time
+type lineReader interface {
+	// readLine returns the next line without its newline, io.EOF at the end
+	// of the input and errInterrupted if the line was abandoned with ctrl-c
+	readLine(prompt string) (string, error)
+}
+
+// plainReader reads lines from anything that isn't a terminal
+type plainReader struct {
+	scanner *bufio.Scanner
+	output  io.Writer
+}
+
+func (reader plainReader) readLine(prompt string) (string, error) {
+	fmt.Fprint(reader.output, prompt)
+
+	if !reader.scanner.Scan() {
+		if err := reader.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+
+	return reader.scanner.Text(), nil
+}
+
+// editor reads lines from a terminal and lets them be edited like in a shell:
+// the arrow keys move around and through the history, ctrl-r searches the
+// history and tab completes the word before the cursor.
+type editor struct {
+	input    *bufio.Reader
+	output   io.Writer
+	raw      func() (restore func(), err error) // puts the terminal in raw mode
+	width    func() int                         // columns of the terminal
+	complete func(line []rune, cursor int) (start int, candidates []string)
+
+	history     []string
+	historyFile string // new lines are appended to it, not saved if ""
+
+	line   []rune
+	cursor int
+}
+
+// newEditor creates an editor for the terminal file, with the history saved
+// by earlier sessions
+func newEditor(file *os.File, output io.Writer, complete func([]rune, int) (int, []string)) *editor {
+	fd := int(file.Fd())
+
+	editor := &editor{
+		input:       bufio.NewReader(file),
+		output:      output,
+		raw:         func() (func(), error) { return makeRaw(fd) },
+		width:       func() int { return terminalWidth(fd) },
+		complete:    complete,
+		historyFile: historyFile(),
+	}
+
+	editor.loadHistory()
+	return editor
+}
+
+// historyFile returns where the history is kept, in the directory fs.config()
+// gives
+func historyFile() string {
+	dir, err := os.UserConfigDir()
+
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "monkey", "history")
+}
+
+// loadHistory reads the history file, trimming it to the last historyLimit
+// lines if it has grown past that. The history is only a convenience, so
+// errors are ignored.
+func (e *editor) loadHistory() {
+	if e.historyFile == "" {
+		return
+	}
+
+	content, err := ioutil.ReadFile(e.historyFile)
+
+	if err != nil {
+		return
+	}
+
+	e.history = strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+
+	if len(e.history) > historyLimit {
+		e.history = e.history[len(e.history)-historyLimit:]
+		ioutil.WriteFile(e.historyFile, []byte(strings.Join(e.history, "\n")+"\n"), 0600)
+	}
+}
+
+// addHistory adds line to the history unless it's empty or the same as the
+// line before it
+func (e *editor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+
+	e.history = append(e.history, line)
+
+	if e.historyFile == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(e.historyFile), 0700); err != nil {
+		return
+	}
+
+	file, err := os.OpenFile(e.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintln(file, line)
+	file.Close()
+}
+
+func (e *editor) readLine(prompt string) (string, error) {
+	restore, err := e.raw()
+
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	e.line, e.cursor = nil, 0
+	e.render(prompt)
+
+	// where in the history the line is from, len(history) is the new line
+	// which is kept in edited while looking through the history
+	position := len(e.history)
+	edited := ""
+
+	for {
+		key, err := e.readKey()
+
+		if err != nil {
+			return "", err
+		}
+
+		if key == keyCtrlR {
+			if key, err = e.search(); err != nil {
+				return "", err
+			}
+		}
+
+		switch key {
+		case keyEnter, keyNewline:
+			e.cursor = len(e.line)
+			e.render(prompt)
+			io.WriteString(e.output, "\r\n")
+
+			line := string(e.line)
+			e.addHistory(line)
+			return line, nil
+		case keyCtrlC:
+			io.WriteString(e.output, "^C\r\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				io.WriteString(e.output, "\r\n")
+				return "", io.EOF
+			}
+			e.remove(e.cursor, e.cursor+1)
+		case keyDelete:
+			e.remove(e.cursor, e.cursor+1)
+		case keyBackspace, keyCtrlH:
+			e.remove(e.cursor-1, e.cursor)
+		case keyCtrlW:
+			start := e.cursor
+			for start > 0 && e.line[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && e.line[start-1] != ' ' {
+				start--
+			}
+			e.remove(start, e.cursor)
+		case keyCtrlK:
+			e.remove(e.cursor, len(e.line))
+		case keyCtrlU:
+			e.remove(0, e.cursor)
+		case keyLeft, keyCtrlB:
+			if e.cursor > 0 {
+				e.cursor--
+			}
+		case keyRight, keyCtrlF:
+			if e.cursor < len(e.line) {
+				e.cursor++
+			}
+		case keyHome, keyHomeVt, keyCtrlA:
+			e.cursor = 0
+		case keyEnd, keyEndVt, keyCtrlE:
+			e.cursor = len(e.line)
+		case keyUp, keyCtrlP:
+			if position > 0 {
+				if position == len(e.history) {
+					edited = string(e.line)
+				}
+				position--
+				e.setLine(e.history[position])
+			}
+		case keyDown, keyCtrlN:
+			if position < len(e.history) {
+				position++
+				if position == len(e.history) {
+					e.setLine(edited)
+				} else {
+					e.setLine(e.history[position])
+				}
+			}
+		case keyTab:
+			e.completeWord(prompt)
+		default:
+			if isPrintable(key) {
+				e.insert([]rune(key))
+			}
+		}
+
+		e.render(prompt)
+	}
+}
+
+// readKey reads one key press, either a character or an escape sequence.
+// Sequences starting with "\x1bO" are read as if they started with "\x1b[",
+// terminals send those for the arrow keys in some modes.
+func (e *editor) readKey() (string, error) {
+	char, _, err := e.input.ReadRune()
+
+	if err != nil || char != '\x1b' {
+		return string(char), err
+	}
+
+	if char, _, err = e.input.ReadRune(); err != nil {
+		return "", err
+	}
+
+	if char != '[' && char != 'O' {
+		// alt and a key, nothing uses them
+		return "\x1b" + string(char), nil
+	}
+
+	sequence := "\x1b["
+
+	for {
+		if char, _, err = e.input.ReadRune(); err != nil {
+			return "", err
+		}
+
+		sequence += string(char)
+
+		// parameters are digits and ";", the sequence ends with anything else
+		if char >= 0x40 && char <= 0x7e {
+			return sequence, nil
+		}
+	}
+}
+
+// search is the reverse history search of ctrl-r. Typing narrows the search,
+// ctrl-r again goes to an older match and ctrl-g gives up. Any other key
+// leaves the match in the line and is returned to be handled as usual.
+func (e *editor) search() (string, error) {
+	original := string(e.line)
+	var query []rune
+	match := len(e.history)
+	failed := false
+
+	for {
+		prompt := fmt.Sprintf("(reverse-i-search)`%v': ", string(query))
+		if failed {
+			prompt = "(failed " + prompt[1:]
+		}
+		e.render(prompt)
+
+		key, err := e.readKey()
+
+		if err != nil {
+			return "", err
+		}
+
+		from := match + 1
+
+		switch {
+		case key == keyCtrlR:
+			from = match
+		case key == keyBackspace || key == keyCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+			}
+			from = len(e.history)
+		case key == keyCtrlG:
+			e.setLine(original)
+			return "", nil
+		case isPrintable(key):
+			query = append(query, []rune(key)...)
+		default:
+			return key, nil
+		}
+
+		found := e.find(string(query), from)
+		failed = found == -1
+
+		if !failed {
+			match = found
+			e.setLine(e.history[match])
+			e.cursor = len([]rune(e.history[match][:strings.Index(e.history[match], string(query))]))
+		}
+	}
+}
+
+// find returns the index of the latest history line before before that
+// contains query, -1 if there is none
+func (e *editor) find(query string, before int) int {
+	if before > len(e.history) {
+		before = len(e.history)
+	}
+
+	for i := before - 1; i >= 0; i-- {
+		if strings.Contains(e.history[i], query) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// completeWord completes the word before the cursor. If there are several
+// ways to complete it, the part they share is added and if that's nothing
+// they are listed. Without a word a tab is inserted to indent the line.
+func (e *editor) completeWord(prompt string) {
+	start, candidates := e.complete(e.line, e.cursor)
+
+	if start == e.cursor {
+		e.insert([]rune(keyTab))
+		return
+	}
+
+	word := string(e.line[start:e.cursor])
+
+	switch len(candidates) {
+	case 0:
+		io.WriteString(e.output, "\a")
+	case 1:
+		e.insert([]rune(candidates[0][len(word):]))
+	default:
+		prefix := commonPrefix(candidates)
+
+		if len(prefix) > len(word) {
+			e.insert([]rune(prefix[len(word):]))
+			return
+		}
+
+		e.cursor = len(e.line)
+		e.render(prompt)
+		io.WriteString(e.output, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+		e.cursor = start + len([]rune(word))
+	}
+}
+
+// commonPrefix is the longest prefix all words share, it never ends in the
+// middle of a character
+func commonPrefix(words []string) string {
+	prefix := []rune(words[0])
+
+	for _, word := range words[1:] {
+		length := 0
+		for _, char := range word {
+			if length == len(prefix) || prefix[length] != char {
+				break
+			}
+			length++
+		}
+		prefix = prefix[:length]
+	}
+
+	return string(prefix)
+}
+
+func (e *editor) insert(text []rune) {
+	line := append([]rune{}, e.line[:e.cursor]...)
+	line = append(line, text...)
+	e.line = append(line, e.line[e.cursor:]...)
+	e.cursor += len(text)
+}
+
+// remove deletes the characters from start up to end, both are kept within
+// the line
+func (e *editor) remove(start int, end int) {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(e.line) {
+		end = len(e.line)
+	}
+	if start >= end {
+		return
+	}
+
+	e.line = append(e.line[:start], e.line[end:]...)
+
+	if e.cursor > end {
+		e.cursor -= end - start
+	} else if e.cursor > start {
+		e.cursor = start
+	}
+}
+
+func (e *editor) setLine(line string) {
+	e.line = []rune(line)
+	e.cursor = len(e.line)
+}
+
+// render redraws the line with the cursor in place. Tabs are drawn as
+// spaces, and a line wider than the terminal scrolls sideways to keep the
+// cursor on screen.
+func (e *editor) render(prompt string) {
+	var text []rune
+	column := 0
+
+	for i, char := range append([]rune(prompt), e.line...) {
+		if i == len([]rune(prompt))+e.cursor {
+			column = len(text)
+		}
+
+		if char == '\t' {
+			text = append(text, []rune(strings.Repeat(" ", 8-len(text)%8))...)
+		} else {
+			text = append(text, char)
+		}
+	}
+
+	if e.cursor == len(e.line) {
+		column = len(text)
+	}
+
+	width := e.width() - 1
+	start := 0
+
+	if column > width {
+		start = column - width
+	}
+
+	end := start + width
+	if end > len(text) {
+		end = len(text)
+	}
+
+	screen := "\r" + string(text[start:end]) + "\x1b[K\r"
+
+	if column > start {
+		screen += fmt.Sprintf("\x1b[%vC", column-start)
+	}
+
+	io.WriteString(e.output, screen)
+}
+
+func isPrintable(key string) bool {
+	for _, char := range key {
+		if char < ' ' || char == 0x7f {
+			return false
+		}
+	}
+	return key != ""
+}
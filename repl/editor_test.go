@@ -0,0 +1,117 @@
+package repl
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"../object"
+)
+
+func testEditor(keys string, history []string) *editor {
+	env := object.NewEnvironment()
+	env.Set("counter", object.Number(1))
+	env.Set("größe", object.Number(2))
+	env.Set("grün", object.Number(3))
+
+	return &editor{
+		input:    bufio.NewReader(strings.NewReader(keys)),
+		output:   ioutil.Discard,
+		raw:      func() (func(), error) { return func() {}, nil },
+		width:    func() int { return 80 },
+		history:  history,
+		complete: func(line []rune, cursor int) (int, []string) { return complete(line, cursor, env) },
+	}
+}
+
+func TestEditor(t *testing.T) {
+	history := []string{`print("one")`, "x = 2", `print("two")`}
+
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"abc\r", "abc"},
+		{"ac" + keyLeft + "b" + keyEnd + "d\r", "abcd"},
+		{"abc" + keyBackspace + keyCtrlA + keyDelete + "\r", "b"},
+		{"one two" + keyCtrlW + "three\r", "one three"},
+		{"abc" + keyLeft + keyCtrlK + "\r", "ab"},
+		{"abc" + keyLeft + keyCtrlU + "\r", "c"},
+		{"größe" + keyLeft + keyLeft + "x\r", "gröxße"},
+		{keyUp + "\r", `print("two")`},
+		{keyUp + keyUp + keyUp + keyUp + "\r", `print("one")`},
+		{"new" + keyUp + keyDown + "\r", "new"},
+		{keyCtrlR + "print\r", `print("two")`},
+		{keyCtrlR + "print" + keyCtrlR + "\r", `print("one")`},
+		{keyCtrlR + "x" + keyEnd + " + 1\r", "x = 2 + 1"},
+		{"y" + keyCtrlR + "nothing" + keyCtrlG + "\r", "y"},
+		{"cou\t\r", "counter"},
+		{"fs.gl\t()\r", "fs.glob()"},
+		{"re\t\r", "re"}, // record and return
+		{"\tx\r", "\tx"},
+		{"g\t\r", "gr"}, // größe and grün
+		{"grü\t\r", "grün"},
+	}
+
+	for i, test := range tests {
+		line, err := testEditor(test.keys, history).readLine(PROMPT)
+
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error %v", i, err)
+		}
+		if line != test.expected {
+			t.Fatalf("tests[%d] - expected %q, got %q", i, test.expected, line)
+		}
+	}
+
+	if _, err := testEditor("abc"+keyCtrlC, nil).readLine(PROMPT); err != errInterrupted {
+		t.Fatalf("expected ctrl-c to interrupt, got %v", err)
+	}
+	if _, err := testEditor(keyCtrlD, nil).readLine(PROMPT); err != io.EOF {
+		t.Fatalf("expected ctrl-d to end the input, got %v", err)
+	}
+
+	editor := testEditor("a\ra\r\rb\r", nil)
+	for i := 0; i < 4; i++ {
+		editor.readLine(PROMPT)
+	}
+	if strings.Join(editor.history, ",") != "a,b" {
+		t.Fatalf("expected history a,b, got %v", editor.history)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("config", object.Record{Values: map[string]object.Object{
+		"name":  object.String("x"),
+		"paths": object.Record{Values: map[string]object.Object{"home": object.String("~")}},
+	}})
+
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{"x = con", []string{"config", "continue", "conv"}},
+		{"fs.re", []string{"fs.read"}},
+		{"print(fs.cwd(), string.has_u", []string{"string.has_uncased"}},
+		{"config.", []string{"config.name", "config.paths"}},
+		{"config.paths.h", []string{"config.paths.home"}},
+		{"config.name.x", nil},
+		{"nothing.x", nil},
+		{"x = ", nil},
+	}
+
+	for i, test := range tests {
+		line := []rune(test.line)
+		start, candidates := complete(line, len(line), env)
+
+		if strings.Join(candidates, ",") != strings.Join(test.expected, ",") {
+			t.Fatalf("tests[%d] - expected %v, got %v", i, test.expected, candidates)
+		}
+		if len(candidates) > 0 && !strings.HasPrefix(candidates[0], string(line[start:])) {
+			t.Fatalf("tests[%d] - word starts at %v, not a prefix of %v", i, start, candidates[0])
+		}
+	}
+}
@@ -5,68 +5,198 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
+	"../evaluator"
 	"../lexer"
+	"../object"
+	"../parser"
 	"../tokens"
 )
 
 const PROMPT = ">>> "
+const CONTINUATION_PROMPT = "... "
 
-func Repl() {
-	scanner := bufio.NewScanner(os.Stdin)
-	//env := object.NewEnvironment()
+// Mode is how far the input goes through the interpreter before the result is
+// printed, useful for seeing what the lexer and parser make of a snippet
+type Mode int
+
+const (
+	TOKENS Mode = iota // lex
+	AST                // parse
+	EVAL               // evaluate
+)
+
+var modeNames = map[Mode]string{
+	TOKENS: "tokens",
+	AST:    "ast",
+	EVAL:   "eval",
+}
+
+func (mode Mode) String() string {
+	return modeNames[mode]
+}
+
+// ParseMode returns the mode with the given name
+func ParseMode(name string) (Mode, error) {
+	for mode, modeName := range modeNames {
+		if name == modeName {
+			return mode, nil
+		}
+	}
+	return EVAL, fmt.Errorf("unknown mode %q, expected tokens, ast or eval", name)
+}
+
+const help = `:tokens  print the tokens of the input
+:ast     print the syntax tree of the input
+:eval    run the input
+:mode    show the current mode
+:help    show this help`
+
+// Repl reads input line by line and prints what the stage of mode makes of
+// it. Input that isn't complete yet, like a function without its end, is read
+// on until it is and then handled as one. Lines starting with ":" are
+// commands, see :help. Everything that is run shares one environment, and
+// errors are printed without stopping the repl. On a terminal lines can be
+// edited, with history and tab completion, see editor.
+func Repl(input io.Reader, output io.Writer, mode Mode) {
+	env := object.NewEnvironment()
+	history := &history{}
+	var reader lineReader = plainReader{bufio.NewScanner(input), output}
+
+	if file, ok := input.(*os.File); ok && isTerminal(int(file.Fd())) {
+		reader = newEditor(file, output, func(line []rune, cursor int) (int, []string) {
+			return complete(line, cursor, env)
+		})
+	}
+
+	var lines []string
 
 	for {
-		fmt.Print(PROMPT)
+		prompt := PROMPT
+		if len(lines) > 0 {
+			prompt = CONTINUATION_PROMPT
+		}
+
+		line, err := reader.readLine(prompt)
 
-		if !scanner.Scan() {
+		if err == errInterrupted {
+			lines = nil
+			continue
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintln(output, err)
+			}
 			break
 		}
 
-		//line := scanner.Text()
-		//lex := lexer.New(line)
-		//pars := parser.New(lex)
-		//
-		//program := pars.ParseProgram()
-		//
-		//if pars.HasErrors() {
-		//	for _, err := range pars.Errors() {
-		//		fmt.Println("Error: " + err)
-		//	}
-		//	continue
-		//}
-		//
-		//result := evaluator.Eval(program, env)
-		//
-		//if result == nil {
-		//	//fmt.Println("eval returned nil")
-		//} else {
-		//	fmt.Println(result.String())
-		//}
+		if command := strings.TrimSpace(line); len(lines) == 0 && strings.HasPrefix(command, ":") {
+			switch command {
+			case ":mode":
+				fmt.Fprintln(output, mode)
+			case ":help":
+				fmt.Fprintln(output, help)
+			default:
+				newMode, err := ParseMode(command[1:])
+				if err != nil {
+					fmt.Fprintf(output, "unknown command %v, see :help\n", command)
+					continue
+				}
+				mode = newMode
+			}
+			continue
+		}
+
+		lines = append(lines, line)
+		source := strings.Join(lines, "\n")
+
+		if incomplete(source) {
+			continue
+		}
+
+		lines = nil
+		run(source, output, mode, env, history)
+	}
+
+	// whatever was left when the input ended, so its errors are shown
+	if len(lines) > 0 {
+		fmt.Fprintln(output)
+		run(strings.Join(lines, "\n"), output, mode, env, history)
 	}
 }
 
-func Rlpl(input io.Reader) {
-	scanner := bufio.NewScanner(input)
+// history is all the input parsed in the repl so far. Each input is numbered
+// on from the lines before it, so errors can show their line even when it's
+// from an earlier input, like the body of a function defined there.
+type history struct {
+	lines []string
+}
 
-	for {
-		fmt.Print(PROMPT)
+// add adds source to the history and returns the line it starts on
+func (h *history) add(source string) int {
+	start := len(h.lines) + 1
+	h.lines = append(h.lines, strings.Split(source, "\n")...)
+	return start
+}
 
-		if !scanner.Scan() {
-			break
+func (h *history) String() string {
+	return strings.Join(h.lines, "\n")
+}
+
+func run(source string, output io.Writer, mode Mode, env *object.Environment, history *history) {
+	if mode == TOKENS {
+		DumpTokens(strings.NewReader(source), output, "text")
+		return
+	}
+
+	lex := lexer.New(source)
+	lex.StartAt(history.add(source))
+	pars := parser.New(lex)
+	program := pars.ParseProgram()
+
+	if pars.HasErrors() {
+		for _, err := range pars.Errors() {
+			fmt.Fprintln(output, err.Format("<repl>", history.String()))
 		}
+		return
+	}
 
-		line := scanner.Text()
-		lex := lexer.New(line)
+	if mode == AST {
+		fmt.Fprint(output, program.String(0))
+		return
+	}
 
-		for {
-			token := lex.NextToken()
+	switch result := evaluator.Eval(program, env).(type) {
+	case nil, object.Nil:
+		// nothing worth showing
+	case object.Error:
+		fmt.Fprintln(output, result.Format("<repl>", history.String()))
+	default:
+		fmt.Fprintln(output, result.String())
+	}
+}
 
-			if token.Type == tokens.EOF {
-				break
-			}
+// incomplete reports if more lines are needed to finish the source: a block
+// without its end, an open bracket or a raw string still going on. Other
+// strings can't span lines, so they are left for the lexer to report.
+func incomplete(source string) bool {
+	lex := lexer.New(source)
+	depth := 0
 
-			fmt.Println(token)
+	for token := lex.NextToken(); token.Type != tokens.EOF; token = lex.NextToken() {
+		switch token.Type {
+		case tokens.FUNC, tokens.IF, tokens.LOOP, tokens.L_PAREN, tokens.L_BRACKET, tokens.L_BRACE:
+			depth++
+		case tokens.END, tokens.R_PAREN, tokens.R_BRACKET, tokens.R_BRACE:
+			depth--
+		case tokens.ILLEGAL:
+			if strings.HasPrefix(source[token.Pos.Offset:], "`") {
+				return true
+			}
 		}
 	}
+
+	return depth > 0
 }
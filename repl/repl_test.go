@@ -0,0 +1,88 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplModes(t *testing.T) {
+	input := "a = 1\n:ast\na = 1 + 2 * 3\nf)\n:tokens\nb\n:mode\n:bogus\n:eval\n:mode\n"
+
+	expected := `>>> >>> >>> a = (1 + (2 * 3))
+>>> <repl>:3:2: expected an expression, found ")"
+f)
+ ^
+>>> >>> 1:1      ident        "b"
+1:2      eof
+>>> tokens
+>>> unknown command :bogus, see :help
+>>> >>> eval
+>>> `
+
+	var output strings.Builder
+	Repl(strings.NewReader(input), &output, EVAL)
+
+	if output.String() != expected {
+		t.Fatalf("expected output:\n%v\ninstead we got:\n%v", expected, output.String())
+	}
+}
+
+func TestReplEval(t *testing.T) {
+	input := "x = 2\nx * 21\nx +\nx + \"a\"\nsquare = func (n) return n * n end\nsquare(x)\n{}[\"y\"]\n"
+
+	expected := `>>> >>> 42
+>>> <repl>:3:4: expected an expression, found end of file
+x +
+   ^
+>>> <repl>:4:1: type mismatch number + string
+x + "a"
+^
+>>> >>> 4
+>>> >>> `
+
+	var output strings.Builder
+	Repl(strings.NewReader(input), &output, EVAL)
+
+	if output.String() != expected {
+		t.Fatalf("expected output:\n%v\ninstead we got:\n%v", expected, output.String())
+	}
+
+	// errors in functions point at where the function was defined
+	input = "f = func () return 1 + \"a\" end\nf()\ng = func (n)\n\treturn n.x\nend\ng(1)\n"
+
+	expected = `>>> >>> <repl>:1:20: type mismatch number + string
+f = func () return 1 + "a" end
+                   ^
+>>> ... ... >>> <repl>:4:9: cannot get field "x" from type number
+	return n.x
+	       ^
+>>> `
+
+	output.Reset()
+	Repl(strings.NewReader(input), &output, EVAL)
+
+	if output.String() != expected {
+		t.Fatalf("expected output:\n%v\ninstead we got:\n%v", expected, output.String())
+	}
+}
+
+func TestReplMultiline(t *testing.T) {
+	input := "square = func (n)\n\treturn n * n\nend\nsquare(3)\n[1,\n2][1]\ns = `a\nb`\ns\n:ast\nif x then\n"
+
+	expected := `>>> ... ... >>> 9
+>>> ... 2
+>>> ... >>> a
+b
+>>> >>> ... 
+<repl>:10:10: expected "end" to close the if statement on line 10, found end of file
+if x then
+         ^
+`
+
+	var output strings.Builder
+	Repl(strings.NewReader(input), &output, EVAL)
+
+	if output.String() != expected {
+		t.Fatalf("expected output:\n%v\ninstead we got:\n%v", expected, output.String())
+	}
+}
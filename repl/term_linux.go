@@ -0,0 +1,60 @@
+package repl
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func ioctl(fd int, request uintptr, argument unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(argument))
+
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// isTerminal reports if fd is a terminal the line editor can be used on
+func isTerminal(fd int) bool {
+	var termios syscall.Termios
+	return ioctl(fd, syscall.TCGETS, unsafe.Pointer(&termios)) == nil
+}
+
+// makeRaw puts the terminal in raw mode, keys are read as they are pressed
+// and nothing is echoed. Output is still processed so "\n" starts a new line.
+func makeRaw(fd int) (restore func(), err error) {
+	var old syscall.Termios
+
+	if err := ioctl(fd, syscall.TCGETS, unsafe.Pointer(&old)); err != nil {
+		return nil, err
+	}
+
+	raw := old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := ioctl(fd, syscall.TCSETS, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		ioctl(fd, syscall.TCSETS, unsafe.Pointer(&old))
+	}, nil
+}
+
+// terminalWidth returns the number of columns of the terminal
+func terminalWidth(fd int) int {
+	var size struct {
+		rows, cols, xPixels, yPixels uint16
+	}
+
+	if ioctl(fd, syscall.TIOCGWINSZ, unsafe.Pointer(&size)) != nil || size.cols == 0 {
+		return 80
+	}
+	return int(size.cols)
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package repl
+
+import "errors"
+
+// the line editor only knows how to set up terminals on linux, elsewhere the
+// repl reads plain lines
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (restore func(), err error) {
+	return nil, errors.New("raw mode is only supported on linux")
+}
+
+func terminalWidth(fd int) int {
+	return 80
+}
@@ -0,0 +1,103 @@
+package repl
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"../lexer"
+	"../tokens"
+)
+
+// jsonToken is how a token looks in the json dump. The literal is the value
+// of the token, like a string with its escapes decoded, so the source of the
+// token is found from offset up to end_offset instead.
+type jsonToken struct {
+	Type      string `json:"type"`
+	Literal   string `json:"literal"`
+	Line      int    `json:"line"`
+	Col       int    `json:"col"`
+	Offset    int    `json:"offset"`
+	EndOffset int    `json:"end_offset"`
+}
+
+// DumpTokens writes every token of the input to output, the final EOF,
+// comments and blank lines included. The format is either "json", one object
+// per line for tools, or "text", one aligned line per token for people.
+func DumpTokens(input io.Reader, output io.Writer, format string) error {
+	if format != "json" && format != "text" {
+		return fmt.Errorf("unknown token format %q, expected json or text", format)
+	}
+
+	writer := bufio.NewWriter(output)
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+
+	write := func(token tokens.Token) error {
+		if format == "json" {
+			return encoder.Encode(jsonToken{
+				Type:      token.Type.String(),
+				Literal:   token.Literal,
+				Line:      token.Pos.Line,
+				Col:       token.Pos.Col,
+				Offset:    token.Pos.Offset,
+				EndOffset: token.EndOffset,
+			})
+		}
+
+		_, err := fmt.Fprintln(writer, formatToken(token))
+		return err
+	}
+
+	lex := lexer.NewReader(input)
+	lex.KeepTrivia()
+
+	for {
+		token := lex.NextToken()
+		var all []tokens.Token
+
+		if token.Trivia != nil {
+			all = append(all, token.Trivia.Leading...)
+		}
+
+		all = append(all, token)
+
+		if token.Trivia != nil && token.Trivia.Trailing != nil {
+			all = append(all, *token.Trivia.Trailing)
+		}
+
+		for _, token := range all {
+			if err := write(token); err != nil {
+				return err
+			}
+		}
+
+		if token.Type == tokens.EOF {
+			break
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	return lex.Err()
+}
+
+// formatToken writes a token as "line:col  type  literal", with the literal
+// quoted so whitespace in it can be seen
+func formatToken(token tokens.Token) string {
+	line := fmt.Sprintf("%-8v %-12v", token.Pos, token.Type)
+
+	switch {
+	case token.Literal != "",
+		token.Type == tokens.STRING, token.Type == tokens.STRING_START,
+		token.Type == tokens.STRING_MID, token.Type == tokens.STRING_END:
+		line += " " + strconv.Quote(token.Literal)
+	}
+
+	return strings.TrimRight(line, " ")
+}
@@ -0,0 +1,50 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDumpTokens(t *testing.T) {
+	input := "größe = \"a\\n\" # note\nf()"
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"json", `{"type":"ident","literal":"größe","line":1,"col":1,"offset":0,"end_offset":7}
+{"type":"=","literal":"","line":1,"col":7,"offset":8,"end_offset":9}
+{"type":"string","literal":"a\n","line":1,"col":9,"offset":10,"end_offset":15}
+{"type":"comment","literal":"# note","line":1,"col":15,"offset":16,"end_offset":22}
+{"type":"ident","literal":"f","line":2,"col":1,"offset":23,"end_offset":24}
+{"type":"(","literal":"","line":2,"col":2,"offset":24,"end_offset":25}
+{"type":")","literal":"","line":2,"col":3,"offset":25,"end_offset":26}
+{"type":"eof","literal":"","line":2,"col":4,"offset":26,"end_offset":26}
+`},
+		{"text", `1:1      ident        "größe"
+1:7      =
+1:9      string       "a\n"
+1:15     comment      "# note"
+2:1      ident        "f"
+2:2      (
+2:3      )
+2:4      eof
+`},
+	}
+
+	for _, test := range tests {
+		var output strings.Builder
+
+		if err := DumpTokens(strings.NewReader(input), &output, test.format); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if output.String() != test.expected {
+			t.Fatalf("expected %v dump:\n%v\ninstead we got:\n%v", test.format, test.expected, output.String())
+		}
+	}
+
+	if err := DumpTokens(strings.NewReader(input), &strings.Builder{}, "xml"); err == nil {
+		t.Fatalf("expected an error for an unknown format")
+	}
+}
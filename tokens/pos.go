@@ -0,0 +1,53 @@
+package tokens
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pos is a position in the source, lines and columns start at 1 and columns
+// count unicode characters. Offset counts bytes from the start of the source.
+type Pos struct {
+	Line   int
+	Col    int
+	Offset int
+}
+
+func (p Pos) IsValid() bool { return p.Line > 0 }
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%v:%v", p.Line, p.Col)
+}
+
+// FormatError formats message as "file:line:col: message" followed by the
+// source line pos points at, with the column underlined
+func FormatError(filename string, source string, pos Pos, message string) string {
+	if !pos.IsValid() {
+		return fmt.Sprintf("%v: %v", filename, message)
+	}
+
+	header := fmt.Sprintf("%v:%v: %v", filename, pos, message)
+	lines := strings.Split(source, "\n")
+
+	if pos.Line > len(lines) {
+		return header
+	}
+
+	line := strings.TrimRight(lines[pos.Line-1], "\r")
+	var underline strings.Builder
+
+	// keep tabs so the marker lines up with the source line
+	for i, char := range []rune(line) {
+		if i >= pos.Col-1 {
+			break
+		}
+
+		if char == '\t' {
+			underline.WriteRune('\t')
+		} else {
+			underline.WriteRune(' ')
+		}
+	}
+
+	return fmt.Sprintf("%v\n%v\n%v^", header, line, underline.String())
+}
@@ -1,18 +1,25 @@
 package tokens
 
-import "fmt"
+import (
+	"fmt"
+	"unicode"
+)
 
 type TokenType int
 
 type Token struct {
-	Type    TokenType
-	Literal string
-	Pos     Pos
+	Type      TokenType
+	Literal   string
+	Pos       Pos
+	EndOffset int     // in bytes, just past the last character of the token
+	Raw       string  // numbers and strings as written, like 0x1F or `a\b`
+	Trivia    *Trivia // nil unless the lexer keeps trivia and there is some
 }
 
-type Pos struct {
-	Line int
-	Col  int
+// Trivia is what the lexer skips that tools might want back, like comments
+type Trivia struct {
+	Leading  []Token // COMMENT and BLANK_LINE tokens on the lines before the token
+	Trailing *Token  // a COMMENT after the token on the same line
 }
 
 const (
@@ -20,11 +27,18 @@ const (
 	ILLEGAL TokenType = iota
 	EOF
 	COMMENT
+	BLANK_LINE // one or more empty lines, only seen as trivia
 
 	IDENT
 	NUMBER
 	STRING
 
+	// interpolated strings are split into parts around the expressions, so
+	// "a {b} c {d} e" is STRING_START, b, STRING_MID, d, STRING_END
+	STRING_START // "a {
+	STRING_MID   // } c {
+	STRING_END   // } e"
+
 	ASSIGN
 
 	ADD
@@ -71,6 +85,7 @@ const (
 	ELSEIF
 	RETURN
 	END
+	LOCAL
 
 	TRUE
 	FALSE
@@ -80,14 +95,19 @@ const (
 )
 
 var tokenNames = [...]string{
-	ILLEGAL: "illegal",
-	EOF:     "eof",
-	COMMENT: "comment",
+	ILLEGAL:    "illegal",
+	EOF:        "eof",
+	COMMENT:    "comment",
+	BLANK_LINE: "blank_line",
 
 	IDENT:  "ident",
 	NUMBER: "number",
 	STRING: "string",
 
+	STRING_START: "string_start",
+	STRING_MID:   "string_mid",
+	STRING_END:   "string_end",
+
 	ASSIGN: "=",
 
 	ADD: "+",
@@ -132,6 +152,7 @@ var tokenNames = [...]string{
 	ELSEIF:   "elseif",
 	RETURN:   "return",
 	END:      "end",
+	LOCAL:    "local",
 
 	TRUE:  "true",
 	FALSE: "false",
@@ -159,6 +180,17 @@ func init() {
 	}
 }
 
+// Keywords returns every keyword, in the order they are declared
+func Keywords() []string {
+	var names []string
+
+	for i := keywords_begin + 1; i < keywords_end; i++ {
+		names = append(names, tokenNames[i])
+	}
+
+	return names
+}
+
 func LookupIdentifier(identifier string) TokenType {
 	if token, ok := keywords[identifier]; ok {
 		return token
@@ -166,3 +198,31 @@ func LookupIdentifier(identifier string) TokenType {
 
 	return IDENT
 }
+
+// IsIdentifierStart reports if an identifier can start with char, any
+// letter or "_"
+func IsIdentifierStart(char rune) bool {
+	return unicode.IsLetter(char) || char == '_'
+}
+
+// IsIdentifierPart reports if char can be in an identifier after the first
+// character, digits are allowed there as well
+func IsIdentifierPart(char rune) bool {
+	return IsIdentifierStart(char) || unicode.IsDigit(char)
+}
+
+// IsIdentifier reports if name can be written as a bare identifier, that is
+// it follows the rules above and isn't a keyword
+func IsIdentifier(name string) bool {
+	if name == "" || LookupIdentifier(name) != IDENT {
+		return false
+	}
+
+	for i, char := range name {
+		if i == 0 && !IsIdentifierStart(char) || !IsIdentifierPart(char) {
+			return false
+		}
+	}
+
+	return true
+}